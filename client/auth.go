@@ -0,0 +1,181 @@
+// Copyright 2017 Verizon
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package client
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+type (
+
+	// Provides credentials for each request made to Mesos.
+	// Refresh is called when Mesos rejects the current credentials so they can be reloaded before trying again.
+	Authenticator interface {
+		Authorization() (string, error)
+		Refresh() error
+	}
+
+	// Authenticates with a fixed principal and secret.
+	BasicAuthenticator struct {
+		header string
+	}
+
+	// Authenticates with a fixed token, such as a JWT handed to us at startup.
+	BearerAuthenticator struct {
+		header string
+	}
+
+	// Authenticates with credentials read from a file, picking up changes whenever the file is rotated.
+	FileAuthenticator struct {
+		path    string
+		bearer  bool
+		header  string
+		modTime time.Time
+		lock    sync.Mutex
+	}
+
+	// Mesos credential files can hold a principal and secret as JSON.
+	credential struct {
+		Principal string `json:"principal"`
+		Secret    string `json:"secret"`
+	}
+)
+
+// Creates an authenticator using HTTP basic authentication.
+func NewBasicAuthenticator(principal, secret string) *BasicAuthenticator {
+	return &BasicAuthenticator{
+		header: basicHeader(principal, secret),
+	}
+}
+
+func (b *BasicAuthenticator) Authorization() (string, error) {
+	return b.header, nil
+}
+
+// Static credentials have nothing to refresh.
+func (b *BasicAuthenticator) Refresh() error {
+	return nil
+}
+
+// Creates an authenticator that sends a bearer token.
+func NewBearerAuthenticator(token string) *BearerAuthenticator {
+	return &BearerAuthenticator{
+		header: "Bearer " + token,
+	}
+}
+
+func (b *BearerAuthenticator) Authorization() (string, error) {
+	return b.header, nil
+}
+
+// Static credentials have nothing to refresh.
+func (b *BearerAuthenticator) Refresh() error {
+	return nil
+}
+
+// Creates an authenticator that reads a principal and secret from a file.
+// The file can either be JSON with "principal" and "secret" keys or the principal and secret separated by whitespace.
+func NewBasicFileAuthenticator(path string) *FileAuthenticator {
+	return &FileAuthenticator{
+		path: path,
+	}
+}
+
+// Creates an authenticator that reads a bearer token from a file.
+func NewBearerFileAuthenticator(path string) *FileAuthenticator {
+	return &FileAuthenticator{
+		path:   path,
+		bearer: true,
+	}
+}
+
+// Gets the credentials, reloading them if the file has changed since we last read it.
+func (f *FileAuthenticator) Authorization() (string, error) {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+
+	info, err := os.Stat(f.path)
+	if err != nil {
+		return "", err
+	}
+
+	if f.header == "" || !info.ModTime().Equal(f.modTime) {
+		if err := f.load(info.ModTime()); err != nil {
+			return "", err
+		}
+	}
+
+	return f.header, nil
+}
+
+// Forces the credentials to be read from the file again.
+func (f *FileAuthenticator) Refresh() error {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+
+	info, err := os.Stat(f.path)
+	if err != nil {
+		return err
+	}
+
+	return f.load(info.ModTime())
+}
+
+// Reads and parses the credential file.
+func (f *FileAuthenticator) load(modTime time.Time) error {
+	data, err := ioutil.ReadFile(f.path)
+	if err != nil {
+		return err
+	}
+
+	content := strings.TrimSpace(string(data))
+	if content == "" {
+		return errors.New("Credential file " + f.path + " is empty")
+	}
+
+	if f.bearer {
+		f.header = "Bearer " + content
+		f.modTime = modTime
+
+		return nil
+	}
+
+	var c credential
+	if err := json.Unmarshal([]byte(content), &c); err != nil {
+		fields := strings.Fields(content)
+		if len(fields) != 2 {
+			return errors.New("Credential file " + f.path + " must contain a principal and a secret")
+		}
+
+		c.Principal, c.Secret = fields[0], fields[1]
+	}
+
+	f.header = basicHeader(c.Principal, c.Secret)
+	f.modTime = modTime
+
+	return nil
+}
+
+// Builds the Authorization header value for basic authentication.
+func basicHeader(principal, secret string) string {
+	return "Basic " + base64.StdEncoding.EncodeToString([]byte(principal+":"+secret))
+}
@@ -0,0 +1,131 @@
+// Copyright 2017 Verizon
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package client
+
+import (
+	"github.com/verizonlabs/mesos-framework-sdk/include/mesos_v1_scheduler"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// Writes out a temporary credential file.
+func writeCredentials(t *testing.T, dir, content string) string {
+	path := filepath.Join(dir, "credentials")
+	if err := ioutil.WriteFile(path, []byte(content), 0600); err != nil {
+		t.Fatal(err.Error())
+	}
+
+	return path
+}
+
+// Makes sure our static authenticators produce the right headers.
+func TestStaticAuthenticators(t *testing.T) {
+	t.Parallel()
+
+	header, _ := NewBasicAuthenticator("principal", "secret").Authorization()
+	if header != "Basic cHJpbmNpcGFsOnNlY3JldA==" {
+		t.Fatal("Basic authorization header is wrong: " + header)
+	}
+
+	header, _ = NewBearerAuthenticator("token").Authorization()
+	if header != "Bearer token" {
+		t.Fatal("Bearer authorization header is wrong: " + header)
+	}
+}
+
+// Checks that both credential file formats are understood.
+func TestFileAuthenticator_Authorization(t *testing.T) {
+	t.Parallel()
+
+	dir, err := ioutil.TempDir("", "auth")
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	defer os.RemoveAll(dir)
+
+	path := writeCredentials(t, dir, `{"principal": "principal", "secret": "secret"}`)
+	header, err := NewBasicFileAuthenticator(path).Authorization()
+	if err != nil || header != "Basic cHJpbmNpcGFsOnNlY3JldA==" {
+		t.Fatal("JSON credentials were not read correctly")
+	}
+
+	path = writeCredentials(t, dir, "principal secret\n")
+	header, err = NewBasicFileAuthenticator(path).Authorization()
+	if err != nil || header != "Basic cHJpbmNpcGFsOnNlY3JldA==" {
+		t.Fatal("Plain text credentials were not read correctly")
+	}
+
+	path = writeCredentials(t, dir, "token\n")
+	header, err = NewBearerFileAuthenticator(path).Authorization()
+	if err != nil || header != "Bearer token" {
+		t.Fatal("Token was not read correctly")
+	}
+
+	_, err = NewBasicFileAuthenticator(path).Authorization()
+	if err == nil {
+		t.Fatal("A lone token is not a valid basic credential")
+	}
+
+	_, err = NewBearerFileAuthenticator(filepath.Join(dir, "missing")).Authorization()
+	if err == nil {
+		t.Fatal("Missing credential files should fail")
+	}
+}
+
+// Ensures the client picks up rotated credentials after being rejected.
+func TestDefaultClient_RequestRefreshesCredentials(t *testing.T) {
+	t.Parallel()
+
+	dir, err := ioutil.TempDir("", "auth")
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	defer os.RemoveAll(dir)
+
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer new" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer ts.Close()
+
+	path := writeCredentials(t, dir, "old")
+	auth := NewBearerFileAuthenticator(path)
+	c := NewClient(ClientData{
+		Endpoint:      ts.URL,
+		Authenticator: auth,
+	}, l)
+
+	_, err = c.Request(&mesos_v1_scheduler.Call{})
+	if err == nil {
+		t.Fatal("Old credentials should have been rejected")
+	}
+
+	// Rotate the token without touching the modification time so only a refresh will see it.
+	info, _ := os.Stat(path)
+	writeCredentials(t, dir, "new")
+	os.Chtimes(path, info.ModTime(), info.ModTime())
+
+	_, err = c.Request(&mesos_v1_scheduler.Call{})
+	if err != nil {
+		t.Fatal("Credentials should have been refreshed after being rejected: " + err.Error())
+	}
+}
@@ -16,17 +16,18 @@ package client
 
 import (
 	"bytes"
+	"context"
 	"errors"
-	"io/ioutil"
 	"github.com/verizonlabs/mesos-framework-sdk/include/mesos_v1_executor"
 	"github.com/verizonlabs/mesos-framework-sdk/include/mesos_v1_scheduler"
 	"github.com/verizonlabs/mesos-framework-sdk/logging"
+	"github.com/verizonlabs/mesos-framework-sdk/recordio"
+	"io/ioutil"
 	"net"
 	"net/http"
 	"strings"
+	"sync"
 	"time"
-
-	"github.com/golang/protobuf/proto"
 )
 
 type Client interface {
@@ -35,125 +36,360 @@ type Client interface {
 	SetStreamID(string) Client
 }
 
+// Clients that can give up on a call once its context is done, which the default client does.
+// Kept apart from Client so existing implementations don't have to change, use RequestContext to call either kind.
+type ContextClient interface {
+	Client
+	RequestContext(context.Context, interface{}) (*http.Response, error)
+}
+
+// Clients that fail over between masters, such as the default one, can tell us which master they're talking to.
+// Kept apart from Client so existing implementations don't have to change.
+type MasterClient interface {
+	Client
+	Master() string
+}
+
 type ClientData struct {
-	Endpoint string
-	Auth     string
+	Endpoint        string
+	Masters         []string        // Other master endpoints to fail over to, in the same form as Endpoint.
+	Auth            string          // Static Authorization header, ignored if an authenticator is given.
+	Authenticator   Authenticator   // Provides the Authorization header for every request.
+	Retry           RetryPolicy     // Calls are only made once if no policy is given.
+	OnAttempt       AttemptHook     // Called after every attempt at making a call.
+	TLS             *TLSConfig      // Connections are not encrypted if this isn't set.
+	Codec           recordio.Codec  // Wire format for calls and events, defaults to protobuf.
+	Instrumentation Instrumentation // Notified around every call, such as for metrics or tracing.
 }
 
 // HTTP client.
 type DefaultClient struct {
 	streamID string
 	data     ClientData
+	masters  []string
 	client   *http.Client
+	detector *http.Client
 	logger   logging.Logger
+	lock     sync.RWMutex
 }
 
 // Return a new HTTP client.
 func NewClient(data ClientData, logger logging.Logger) Client {
+
+	// We handle redirects ourselves so that we always know which master we're talking to.
+	noRedirects := func(req *http.Request, via []*http.Request) error {
+		return http.ErrUseLastResponse
+	}
+
 	return &DefaultClient{
-		data: data,
+		data:    data,
+		masters: candidates(data),
 		client: &http.Client{
-			Transport: &http.Transport{
-				Dial: (&net.Dialer{
-					Timeout:   10 * time.Second,
-					KeepAlive: 30 * time.Second,
-				}).Dial,
-			},
+			Transport:     newTransport(data.TLS, logger),
+			CheckRedirect: noRedirects,
+		},
+		detector: &http.Client{
+			Transport:     newTransport(data.TLS, logger),
+			Timeout:       5 * time.Second,
+			CheckRedirect: noRedirects,
 		},
 		logger: logger,
 	}
 }
 
+// Creates the transport used to talk to Mesos, encrypting connections if TLS has been configured.
+func newTransport(cfg *TLSConfig, logger logging.Logger) http.RoundTripper {
+	dial := (&net.Dialer{
+		Timeout:   10 * time.Second,
+		KeepAlive: 30 * time.Second,
+	}).Dial
+
+	if cfg != nil {
+		return newTLSTransport(cfg, dial, logger)
+	}
+
+	return &http.Transport{
+		Dial: dial,
+	}
+}
+
 // Makes a new request with data and sends it to the server.
 // Determines whether the request/response should be handled for an executor or a scheduler.
+// Scheduler calls are transparently sent to the new leader on redirects and to the next candidate master
+// if the current one cannot be dialed.
 func (c *DefaultClient) Request(call interface{}) (*http.Response, error) {
+	return c.RequestContext(context.Background(), call)
+}
+
+// Sends a call through any client, cancelling it along with the context if the client supports it.
+// Clients that don't can only be stopped from making the call if the context is already done.
+func RequestContext(ctx context.Context, c Client, call interface{}) (*http.Response, error) {
+	if c, ok := c.(ContextClient); ok {
+		return c.RequestContext(ctx, call)
+	}
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	return c.Request(call)
+}
+
+// Same as Request but the in-flight request is cancelled along with the context.
+// For streaming responses the context stays attached to the response body until it's closed.
+func (c *DefaultClient) RequestContext(ctx context.Context, call interface{}) (*http.Response, error) {
 	var data []byte
 	var err error
 	var executorCall bool
 
 	switch call := call.(type) {
 	case *mesos_v1_scheduler.Call:
-		data, err = proto.Marshal(call)
+		data, err = c.codec().Marshal(call)
 	case *mesos_v1_executor.Call:
-		data, err = proto.Marshal(call)
+		data, err = c.codec().Marshal(call)
 		executorCall = true
 	}
 
-	if err != nil {
-		return nil, err
+	o := &Observation{
+		API:          callAPI(call),
+		Call:         callType(call),
+		Start:        time.Now(),
+		RequestBytes: len(data),
 	}
 
-	req, err := http.NewRequest("POST", c.data.Endpoint, bytes.NewReader(data))
-	if err != nil {
-		return nil, err
+	inst := c.data.Instrumentation
+	if inst != nil {
+		ctx = inst.Begin(ctx, o)
 	}
 
-	req.Header.Set("Authorization", c.data.Auth)
-	req.Header.Set("Connection", "keep-alive")
-	req.Header.Set("Content-Type", "application/x-protobuf")
-	req.Header.Set("Accept", "application/x-protobuf")
-	req.Header.Set("Accept-Encoding", "gzip")
-	req.Header.Set("User-Agent", "mesos-framework-sdk")
+	var resp *http.Response
+	if err == nil {
+		resp, o.Attempts, err = c.retry(ctx, call, data, executorCall)
+	}
 
-	// Executors do not use stream IDs.
-	if !executorCall && c.streamID != "" {
-		req.Header.Set("Mesos-Stream-Id", c.streamID)
+	if inst != nil {
+		o.Duration = time.Since(o.Start)
+		o.Err = err
+		if resp != nil {
+			o.StatusCode = resp.StatusCode
+		} else if e, ok := err.(*Error); ok {
+			o.StatusCode = e.StatusCode
+		}
+		inst.End(ctx, o)
 	}
 
-	resp, err := c.client.Do(req)
-	if err != nil {
-		return nil, err
+	return resp, err
+}
+
+// Sends the call until it succeeds or our retry policy gives up, returning the number of attempts made.
+func (c *DefaultClient) retry(ctx context.Context, call interface{}, data []byte, executorCall bool) (*http.Response, int, error) {
+	for attempt := 1; ; attempt++ {
+		resp, err := c.do(ctx, callType(call), data, executorCall)
+
+		var delay time.Duration
+		var retry bool
+		if err != nil && c.data.Retry != nil && ctx.Err() == nil {
+			delay, retry = c.data.Retry.Retry(call, attempt, resp, err)
+		}
+
+		if c.data.OnAttempt != nil {
+			c.data.OnAttempt(Attempt{
+				Number:   attempt,
+				Call:     call,
+				Response: resp,
+				Err:      err,
+				Retry:    retry,
+				Delay:    delay,
+			})
+		}
+
+		if err == nil || !retry {
+			return resp, attempt, err
+		}
+
+		if resp != nil {
+			resp.Body.Close()
+		}
+
+		c.logger.Emit(logging.INFO, "Retrying %s call in %v after attempt %d failed: %s", callType(call), delay, attempt, err.Error())
+
+		select {
+		case <-time.After(delay):
+		case <-ctx.Done():
+			return nil, attempt, ctx.Err()
+		}
 	}
+}
 
-	if resp.StatusCode >= 400 {
-		if resp.StatusCode == 401 {
-			return resp, errors.New("Unauthorized")
+// Makes a single attempt at sending the call, following leader changes along the way.
+func (c *DefaultClient) do(ctx context.Context, call string, data []byte, executorCall bool) (*http.Response, error) {
+
+	// Every master gets a chance, plus one extra attempt to follow a redirect from the last one.
+	attempts := len(c.masters) + 1
+	refreshed := false
+	for attempt := 1; ; attempt++ {
+		auth, err := c.authorization()
+		if err != nil {
+			return nil, errors.New("Failed to get credentials: " + err.Error())
 		}
 
-		data, err := ioutil.ReadAll(resp.Body)
+		endpoint := c.Master()
+		resp, err := c.send(ctx, endpoint, auth, data, executorCall)
 		if err != nil {
-			return resp, err
+
+			// Our master detection only applies to the scheduler.
+			// A cancelled call says nothing about the health of the master either.
+			if executorCall || ctx.Err() != nil {
+				return nil, err
+			}
+
+			c.logger.Emit(logging.ERROR, "Request to master %s failed: %s", endpoint, err.Error())
+			c.failover(ctx, endpoint)
+
+			// Only resend if we know the call never left this host, otherwise we could act on it twice.
+			if isDialError(err) && attempt < attempts {
+				continue
+			}
+
+			return nil, err
 		}
 
-		return resp, errors.New(string(data))
-	}
+		if resp.StatusCode >= 400 {
+			if resp.StatusCode == 401 {
+
+				// Our credentials may have expired or been rotated, get fresh ones and try once more.
+				if c.data.Authenticator != nil && !refreshed {
+					resp.Body.Close()
+					refreshed = true
+
+					if err := c.data.Authenticator.Refresh(); err != nil {
+						return nil, errors.New("Unauthorized, failed to refresh credentials: " + err.Error())
+					}
+
+					continue
+				}
+
+				return resp, newError(resp, call, endpoint, "Unauthorized")
+			}
+
+			data, err := ioutil.ReadAll(resp.Body)
+			if err != nil {
+				return resp, err
+			}
 
-	// Our master detection only applies to the scheduler.
-	if !executorCall {
+			return resp, newError(resp, call, endpoint, string(data))
+		}
+
+		// Our master detection only applies to the scheduler.
+		if executorCall {
+			return resp, nil
+		}
 
 		// We will only get the stream ID after a SUBSCRIBE call.
 		streamID := resp.Header.Get("Mesos-Stream-Id")
 		if streamID != "" {
-			c.streamID = streamID
+			c.SetStreamID(streamID)
 		}
 
 		if resp.StatusCode == http.StatusTemporaryRedirect || resp.StatusCode == http.StatusPermanentRedirect {
-			c.logger.Emit(logging.INFO, "Old master: %s", c.data.Endpoint)
+			resp.Body.Close()
 
 			master := resp.Header.Get("Location")
-			if strings.Contains(master, "http") {
-				c.data.Endpoint = master
-			} else {
-				c.data.Endpoint = resp.Request.URL.Scheme + ":" + master
+			if master == "" {
+				return nil, newError(resp, call, endpoint, "Redirect encountered without a new master location")
 			}
 
-			c.logger.Emit(logging.INFO, "New master: %s", c.data.Endpoint)
+			if !strings.Contains(master, "http") {
+				master = resp.Request.URL.Scheme + ":" + master
+			}
+			c.setMaster(master)
 
-			return nil, errors.New("Redirect encountered, new master found")
+			if attempt < attempts {
+				continue
+			}
+
+			return nil, newError(resp, call, endpoint, "Redirect encountered, new master found")
 		}
+
+		return resp, nil
 	}
+}
 
-	return resp, nil
+// Builds and sends a single request to the given endpoint.
+func (c *DefaultClient) send(ctx context.Context, endpoint, auth string, data []byte, executorCall bool) (*http.Response, error) {
+	req, err := http.NewRequest("POST", endpoint, bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+	req = req.WithContext(ctx)
+
+	req.Header.Set("Authorization", auth)
+	req.Header.Set("Connection", "keep-alive")
+	req.Header.Set("Content-Type", c.codec().ContentType())
+	req.Header.Set("Accept", c.codec().ContentType())
+	req.Header.Set("Accept-Encoding", "gzip")
+	req.Header.Set("User-Agent", "mesos-framework-sdk")
+
+	// Executors do not use stream IDs.
+	streamID := c.StreamID()
+	if !executorCall && streamID != "" {
+		req.Header.Set("Mesos-Stream-Id", streamID)
+	}
+
+	return c.client.Do(req)
+}
+
+// Gets the wire format for our calls, defaulting to protobuf.
+func (c *DefaultClient) codec() recordio.Codec {
+	if c.data.Codec != nil {
+		return c.data.Codec
+	}
+
+	return recordio.Protobuf
+}
+
+// Gets the value of our Authorization header, preferring the authenticator over static credentials.
+func (c *DefaultClient) authorization() (string, error) {
+	if c.data.Authenticator != nil {
+		return c.data.Authenticator.Authorization()
+	}
+
+	return c.data.Auth, nil
+}
+
+// Returns the endpoint of the master we are currently talking to.
+func (c *DefaultClient) Master() string {
+	c.lock.RLock()
+	defer c.lock.RUnlock()
+
+	return c.data.Endpoint
+}
+
+// Switches over to a new master.
+func (c *DefaultClient) setMaster(master string) {
+	c.lock.Lock()
+	old := c.data.Endpoint
+	c.data.Endpoint = master
+	c.lock.Unlock()
+
+	if old != master {
+		c.logger.Emit(logging.INFO, "Old master: %s", old)
+		c.logger.Emit(logging.INFO, "New master: %s", master)
+	}
 }
 
 // Gets our stream ID.
 func (c *DefaultClient) StreamID() string {
+	c.lock.RLock()
+	defer c.lock.RUnlock()
+
 	return c.streamID
 }
 
 // Sets our stream ID.
 func (c *DefaultClient) SetStreamID(id string) Client {
+	c.lock.Lock()
 	c.streamID = id
+	c.lock.Unlock()
 
 	return c
 }
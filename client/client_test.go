@@ -15,12 +15,17 @@
 package client
 
 import (
+	"context"
 	"github.com/verizonlabs/mesos-framework-sdk/include/mesos_v1"
 	"github.com/verizonlabs/mesos-framework-sdk/include/mesos_v1_executor"
 	"github.com/verizonlabs/mesos-framework-sdk/include/mesos_v1_scheduler"
+	"github.com/verizonlabs/mesos-framework-sdk/recordio"
+	"io/ioutil"
 	"net/http"
 	"net/http/httptest"
+	"strings"
 	"testing"
+	"time"
 )
 
 type mockLogger struct{}
@@ -149,6 +154,98 @@ func TestDefaultClient_Request(t *testing.T) {
 	}
 }
 
+// Makes sure requests are abandoned once their context is cancelled.
+func TestDefaultClient_RequestContext(t *testing.T) {
+	t.Parallel()
+
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-r.Context().Done()
+	}))
+	defer ts.Close()
+
+	c := NewClient(ClientData{
+		Endpoint: ts.URL,
+	}, l)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	_, err := RequestContext(ctx, c, &mesos_v1_scheduler.Call{})
+	if err == nil {
+		t.Fatal("Request should have been cancelled")
+	}
+
+	if c.(MasterClient).Master() != ts.URL {
+		t.Fatal("A cancelled request should not cause a failover")
+	}
+}
+
+// Only knows how to make plain requests, like clients written before contexts were supported.
+type plainClient struct {
+	requests int
+}
+
+func (p *plainClient) Request(interface{}) (*http.Response, error) {
+	p.requests++
+	return new(http.Response), nil
+}
+
+func (p *plainClient) StreamID() string {
+	return ""
+}
+
+func (p *plainClient) SetStreamID(string) Client {
+	return p
+}
+
+// Checks that clients without context support still get calls, unless the context is already done.
+func TestRequestContext_PlainClient(t *testing.T) {
+	t.Parallel()
+
+	c := new(plainClient)
+	if _, err := RequestContext(context.Background(), c, &mesos_v1_scheduler.Call{}); err != nil || c.requests != 1 {
+		t.Fatal("The call should have been made through Request")
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := RequestContext(ctx, c, &mesos_v1_scheduler.Call{}); err != context.Canceled || c.requests != 1 {
+		t.Fatal("No call should be made once the context is done")
+	}
+}
+
+// Makes sure calls are sent as JSON when that codec is configured.
+func TestDefaultClient_RequestJSON(t *testing.T) {
+	t.Parallel()
+
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := ioutil.ReadAll(r.Body)
+		if r.Header.Get("Content-Type") != "application/json" || r.Header.Get("Accept") != "application/json" {
+			w.WriteHeader(http.StatusUnsupportedMediaType)
+			return
+		}
+
+		if !strings.Contains(string(body), `"type":"DECLINE"`) {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer ts.Close()
+
+	c := NewClient(ClientData{
+		Endpoint: ts.URL,
+		Codec:    recordio.JSON,
+	}, l)
+
+	_, err := c.Request(&mesos_v1_scheduler.Call{
+		Type: mesos_v1_scheduler.Call_DECLINE.Enum(),
+	})
+	if err != nil {
+		t.Fatal("JSON request could not be made successfully: " + err.Error())
+	}
+}
+
 // Measures performance of creating and sending HTTP requests.
 func BenchmarkDefaultClient_Request(b *testing.B) {
 	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
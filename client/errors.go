@@ -0,0 +1,105 @@
+// Copyright 2017 Verizon
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package client
+
+import (
+	"net"
+	"net/http"
+	"strconv"
+)
+
+// Error is returned when Mesos rejects a call.
+// Callers can branch on the status code or use the predicates below instead of matching on the message.
+type Error struct {
+	StatusCode int
+	Call       string // Type of the call, such as ACCEPT or UPDATE.
+	Endpoint   string // Master or agent that rejected the call.
+	Body       string
+}
+
+// Creates a new error describing a rejected call.
+func newError(resp *http.Response, call, endpoint, body string) *Error {
+	return &Error{
+		StatusCode: resp.StatusCode,
+		Call:       call,
+		Endpoint:   endpoint,
+		Body:       body,
+	}
+}
+
+func (e *Error) Error() string {
+	msg := e.Call + " call to " + e.Endpoint + " failed with status " + strconv.Itoa(e.StatusCode)
+	if e.Body != "" {
+		msg += ": " + e.Body
+	}
+
+	return msg
+}
+
+// Tells us if the call was sent to a master that isn't the leader.
+func (e *Error) IsRedirect() bool {
+	return e.StatusCode == http.StatusTemporaryRedirect || e.StatusCode == http.StatusPermanentRedirect
+}
+
+// Tells us if our credentials were missing or rejected.
+func (e *Error) IsUnauthorized() bool {
+	return e.StatusCode == http.StatusUnauthorized
+}
+
+// Tells us if our credentials were accepted but we aren't allowed to make the call, such as registering with a role.
+func (e *Error) IsForbidden() bool {
+	return e.StatusCode == http.StatusForbidden
+}
+
+// Tells us if the failure is likely to go away on its own, such as when there's no leading master.
+func (e *Error) IsRetryable() bool {
+	switch e.StatusCode {
+	case http.StatusTooManyRequests, http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		return true
+	}
+
+	return false
+}
+
+// Tells us if the error came from a call being sent to a master that isn't the leader.
+func IsRedirect(err error) bool {
+	e, ok := err.(*Error)
+	return ok && e.IsRedirect()
+}
+
+// Tells us if the error came from Mesos rejecting our credentials.
+func IsUnauthorized(err error) bool {
+	e, ok := err.(*Error)
+	return ok && e.IsUnauthorized()
+}
+
+// Tells us if the error came from Mesos not allowing the call.
+func IsForbidden(err error) bool {
+	e, ok := err.(*Error)
+	return ok && e.IsForbidden()
+}
+
+// Tells us if the call may succeed if tried again.
+// Network failures are retryable along with any responses that indicate a temporary problem on the Mesos side.
+func IsRetryable(err error) bool {
+	switch e := err.(type) {
+	case *Error:
+		return e.IsRetryable()
+	case net.Error:
+		return true
+	}
+
+	return false
+}
@@ -0,0 +1,89 @@
+// Copyright 2017 Verizon
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package client
+
+import (
+	"errors"
+	"github.com/verizonlabs/mesos-framework-sdk/include/mesos_v1_scheduler"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+// Makes sure rejected calls come back as typed errors with enough detail to act on.
+func TestDefaultClient_RequestError(t *testing.T) {
+	t.Parallel()
+
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusForbidden)
+		w.Write([]byte("Not authorized to use role"))
+	}))
+	defer ts.Close()
+
+	c := NewClient(ClientData{Endpoint: ts.URL}, l)
+	_, err := c.Request(&mesos_v1_scheduler.Call{Type: mesos_v1_scheduler.Call_SUBSCRIBE.Enum()})
+
+	e, ok := err.(*Error)
+	if !ok {
+		t.Fatalf("Expected a typed error, got %v", err)
+	}
+
+	if e.StatusCode != http.StatusForbidden || e.Call != "SUBSCRIBE" || e.Endpoint != ts.URL || e.Body != "Not authorized to use role" {
+		t.Fatal("Error does not describe the rejected call: " + e.Error())
+	}
+
+	if !IsForbidden(err) || IsUnauthorized(err) || IsRedirect(err) || IsRetryable(err) {
+		t.Fatal("Forbidden error was classified incorrectly")
+	}
+}
+
+// Checks that each status code is classified the way callers expect.
+func TestIsRetryable(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		err       error
+		retryable bool
+	}{
+		{&Error{StatusCode: http.StatusServiceUnavailable}, true},
+		{&Error{StatusCode: http.StatusTooManyRequests}, true},
+		{&Error{StatusCode: http.StatusBadRequest}, false},
+		{&Error{StatusCode: http.StatusUnauthorized}, false},
+		{errors.New("Something else"), false},
+		{nil, false},
+	}
+
+	for _, test := range tests {
+		if IsRetryable(test.err) != test.retryable {
+			t.Fatalf("Expected retryable to be %v for %v", test.retryable, test.err)
+		}
+	}
+
+	if !IsUnauthorized(&Error{StatusCode: http.StatusUnauthorized}) {
+		t.Fatal("Unauthorized error was not detected")
+	}
+
+	if !IsRedirect(&Error{StatusCode: http.StatusTemporaryRedirect}) {
+		t.Fatal("Redirect error was not detected")
+	}
+}
+
+// Measures performance of formatting an error.
+func BenchmarkError_Error(b *testing.B) {
+	err := &Error{StatusCode: http.StatusBadRequest, Call: "ACCEPT", Endpoint: "http://localhost:5050", Body: "Invalid offer"}
+	for n := 0; n < b.N; n++ {
+		_ = err.Error()
+	}
+}
@@ -0,0 +1,202 @@
+// Copyright 2017 Verizon
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"github.com/verizonlabs/mesos-framework-sdk/logging"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+/*
+Leader detection for highly available Mesos masters.
+
+Every master exposes /master/redirect which points at the current leader, and /state which names it.
+We probe the known masters in order and switch over to whichever one they agree is leading.
+*/
+
+// Holds the parts of the master's state that we care about.
+type masterState struct {
+	Leader string `json:"leader"`
+}
+
+// Builds our list of candidate masters, starting with the configured endpoint.
+func candidates(data ClientData) []string {
+	masters := make([]string, 0, len(data.Masters)+1)
+	seen := make(map[string]bool, len(data.Masters)+1)
+	for _, master := range append([]string{data.Endpoint}, data.Masters...) {
+		if master == "" || seen[master] {
+			continue
+		}
+
+		seen[master] = true
+		masters = append(masters, master)
+	}
+
+	return masters
+}
+
+// Tells us if the request failed before it ever reached the master.
+func isDialError(err error) bool {
+	if e, ok := err.(*url.Error); ok {
+		err = e.Err
+	}
+
+	if e, ok := err.(*net.OpError); ok {
+		return e.Op == "dial"
+	}
+
+	return false
+}
+
+// Moves off of a master that failed us, preferring the leader if any of the other masters can tell us who it is.
+// Probes are abandoned along with the request that failed.
+func (c *DefaultClient) failover(ctx context.Context, failed string) {
+	if leader, err := c.DetectLeaderContext(ctx); err == nil {
+		if leader != failed {
+			c.setMaster(leader)
+			return
+		}
+	}
+
+	// Nobody could tell us who the leader is, just try the next master in line.
+	for i, master := range c.masters {
+		if master == failed {
+			c.setMaster(c.masters[(i+1)%len(c.masters)])
+			return
+		}
+	}
+
+	if len(c.masters) > 0 {
+		c.setMaster(c.masters[0])
+	}
+}
+
+// Asks each known master who the leader is and returns the leader's endpoint.
+// The client is switched over to the leader as a side effect.
+func (c *DefaultClient) DetectLeader() (string, error) {
+	return c.DetectLeaderContext(context.Background())
+}
+
+// Same as DetectLeader, but gives up on probing once the context is done.
+func (c *DefaultClient) DetectLeaderContext(ctx context.Context) (string, error) {
+	for _, master := range c.masters {
+		leader, err := c.probe(ctx, master)
+		if ctx.Err() != nil {
+			return "", ctx.Err()
+		}
+		if err != nil {
+			c.logger.Emit(logging.DEBUG, "Could not get the leader from %s: %s", master, err.Error())
+			continue
+		}
+
+		c.setMaster(leader)
+
+		return leader, nil
+	}
+
+	return "", errors.New("No master could tell us who the leader is")
+}
+
+// Asks a single master for the leader, first with /master/redirect and then by looking at /state.
+func (c *DefaultClient) probe(ctx context.Context, master string) (string, error) {
+	u, err := url.Parse(master)
+	if err != nil {
+		return "", err
+	}
+
+	host, err := c.redirectLeader(ctx, u)
+	if err != nil {
+		host, err = c.stateLeader(ctx, u)
+		if err != nil {
+			return "", err
+		}
+	}
+
+	leader := *u
+	leader.Host = host
+
+	return leader.String(), nil
+}
+
+// Gets the leader's address from the Location header of /master/redirect.
+func (c *DefaultClient) redirectLeader(ctx context.Context, u *url.URL) (string, error) {
+	resp, err := c.get(ctx, u.Scheme+"://"+u.Host+"/master/redirect")
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusTemporaryRedirect && resp.StatusCode != http.StatusPermanentRedirect {
+		return "", errors.New("Master did not redirect us to the leader")
+	}
+
+	// Mesos gives us a scheme relative location such as //10.0.0.1:5050.
+	location, err := url.Parse(resp.Header.Get("Location"))
+	if err != nil {
+		return "", err
+	}
+
+	if location.Host == "" {
+		return "", errors.New("Master redirected us without a leader, an election may be in progress")
+	}
+
+	return location.Host, nil
+}
+
+// Gets the leader's address from the master's state, which names it as master@host:port.
+func (c *DefaultClient) stateLeader(ctx context.Context, u *url.URL) (string, error) {
+	resp, err := c.get(ctx, u.Scheme+"://"+u.Host+"/state")
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return "", errors.New("Master state could not be retrieved: " + resp.Status)
+	}
+
+	data, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return "", err
+	}
+
+	var state masterState
+	if err := json.Unmarshal(data, &state); err != nil {
+		return "", err
+	}
+
+	if state.Leader == "" {
+		return "", errors.New("Master does not know of a leader, an election may be in progress")
+	}
+
+	return strings.TrimPrefix(state.Leader, "master@"), nil
+}
+
+// Sends a probe to a master, bounded by both the context and the detector's own timeout.
+func (c *DefaultClient) get(ctx context.Context, endpoint string) (*http.Response, error) {
+	req, err := http.NewRequest("GET", endpoint, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	return c.detector.Do(req.WithContext(ctx))
+}
@@ -0,0 +1,198 @@
+// Copyright 2017 Verizon
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package client
+
+import (
+	"context"
+	"github.com/verizonlabs/mesos-framework-sdk/include/mesos_v1_scheduler"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+// Makes sure our candidate masters are ordered and free of duplicates.
+func TestCandidates(t *testing.T) {
+	t.Parallel()
+
+	masters := candidates(ClientData{
+		Endpoint: "http://a/api/v1/scheduler",
+		Masters:  []string{"http://b/api/v1/scheduler", "http://a/api/v1/scheduler", ""},
+	})
+
+	if len(masters) != 2 || masters[0] != "http://a/api/v1/scheduler" || masters[1] != "http://b/api/v1/scheduler" {
+		t.Fatal("Candidate masters are wrong: " + strings.Join(masters, ","))
+	}
+}
+
+// Ensures we find the leader through /master/redirect.
+func TestDefaultClient_DetectLeaderRedirect(t *testing.T) {
+	t.Parallel()
+
+	leader := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer leader.Close()
+
+	follower := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/master/redirect" {
+			t.Fatal("Unexpected probe to " + r.URL.Path)
+		}
+		w.Header().Set("Location", strings.TrimPrefix(leader.URL, "http:"))
+		w.WriteHeader(http.StatusTemporaryRedirect)
+	}))
+	defer follower.Close()
+
+	c := NewClient(ClientData{
+		Endpoint: follower.URL + "/api/v1/scheduler",
+	}, l).(*DefaultClient)
+
+	master, err := c.DetectLeader()
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	if master != leader.URL+"/api/v1/scheduler" || c.Master() != master {
+		t.Fatal("Wrong leader detected: " + master)
+	}
+}
+
+// Ensures we fall back to /state when the redirect endpoint is not usable.
+func TestDefaultClient_DetectLeaderState(t *testing.T) {
+	t.Parallel()
+
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/state" {
+			w.Write([]byte(`{"leader": "master@10.0.0.1:5050"}`))
+			return
+		}
+		w.WriteHeader(http.StatusNotFound)
+	}))
+	defer ts.Close()
+
+	c := NewClient(ClientData{
+		Endpoint: ts.URL + "/api/v1/scheduler",
+	}, l)
+
+	master, err := c.(*DefaultClient).DetectLeader()
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	if master != "http://10.0.0.1:5050/api/v1/scheduler" {
+		t.Fatal("Wrong leader detected: " + master)
+	}
+
+	ts2 := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{}`))
+	}))
+	defer ts2.Close()
+
+	c = NewClient(ClientData{
+		Endpoint: ts2.URL + "/api/v1/scheduler",
+	}, l)
+
+	_, err = c.(*DefaultClient).DetectLeader()
+	if err == nil {
+		t.Fatal("No leader should have been found during an election")
+	}
+}
+
+// Ensures leader probes give up as soon as the context is done instead of waiting out their timeout.
+func TestDefaultClient_DetectLeaderContext(t *testing.T) {
+	t.Parallel()
+
+	hung := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-r.Context().Done():
+		case <-time.After(5 * time.Second):
+		}
+	}))
+	defer hung.Close()
+
+	c := NewClient(ClientData{
+		Endpoint: hung.URL + "/api/v1/scheduler",
+		Masters:  []string{hung.URL + "/api/v1/other"},
+	}, l).(*DefaultClient)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	start := time.Now()
+	if _, err := c.DetectLeaderContext(ctx); err != context.DeadlineExceeded {
+		t.Fatal("Detection should have been cancelled")
+	}
+
+	if time.Since(start) > time.Second {
+		t.Fatal("Probes did not respect the context")
+	}
+}
+
+// Makes sure a master that can't be dialed is skipped in favor of the next one.
+func TestDefaultClient_RequestFailover(t *testing.T) {
+	t.Parallel()
+
+	down := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	down.Close()
+
+	up := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer up.Close()
+
+	c := NewClient(ClientData{
+		Endpoint: down.URL,
+		Masters:  []string{up.URL},
+	}, l)
+
+	_, err := c.Request(&mesos_v1_scheduler.Call{})
+	if err != nil {
+		t.Fatal("Request should have failed over to the next master: " + err.Error())
+	}
+
+	if c.(MasterClient).Master() != up.URL {
+		t.Fatal("Client did not report the master it failed over to")
+	}
+}
+
+// Makes sure that we follow redirects to the new leader and resend our call there.
+func TestDefaultClient_RequestRedirect(t *testing.T) {
+	t.Parallel()
+
+	leader := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer leader.Close()
+
+	follower := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Location", strings.TrimPrefix(leader.URL, "http:")+r.URL.Path)
+		w.WriteHeader(http.StatusTemporaryRedirect)
+	}))
+	defer follower.Close()
+
+	c := NewClient(ClientData{
+		Endpoint: follower.URL + "/api/v1/scheduler",
+	}, l)
+
+	resp, err := c.Request(&mesos_v1_scheduler.Call{})
+	if err != nil {
+		t.Fatal("Redirect should have been followed: " + err.Error())
+	}
+
+	if resp.StatusCode != http.StatusOK || c.(MasterClient).Master() != leader.URL+"/api/v1/scheduler" {
+		t.Fatal("Call was not resent to the new leader")
+	}
+}
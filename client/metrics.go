@@ -0,0 +1,232 @@
+// Copyright 2017 Verizon
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package client
+
+import (
+	"bytes"
+	"context"
+	"github.com/verizonlabs/mesos-framework-sdk/include/mesos_v1_executor"
+	"github.com/verizonlabs/mesos-framework-sdk/include/mesos_v1_scheduler"
+	"net/http"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+)
+
+type (
+
+	// Instrumentation is notified around every call made through the client.
+	// Begin may return a derived context, such as one carrying a tracing span, which is used for the request.
+	// End is always called with the same observation once the call has finished, including any retries.
+	Instrumentation interface {
+		Begin(ctx context.Context, o *Observation) context.Context
+		End(ctx context.Context, o *Observation)
+	}
+
+	// Describes a single call made to Mesos.
+	Observation struct {
+		API          string // Either "scheduler" or "executor".
+		Call         string // Type of the call, such as ACCEPT or UPDATE.
+		Start        time.Time
+		Duration     time.Duration
+		StatusCode   int // Zero if we never got a response.
+		RequestBytes int // Size of the encoded call.
+		Attempts     int
+		Err          error
+	}
+
+	// Metrics aggregates calls per API and call type and exposes them in the Prometheus text format.
+	// It can be mounted on any mux, such as the one from our server configuration.
+	Metrics struct {
+		buckets []float64
+		series  map[metricsKey]*metricsSeries
+		lock    sync.Mutex
+	}
+
+	metricsKey struct {
+		api  string
+		call string
+	}
+
+	metricsSeries struct {
+		codes        map[int]uint64
+		errors       uint64
+		requestBytes uint64
+		buckets      []uint64
+		sum          float64
+		count        uint64
+	}
+)
+
+// Latency buckets in seconds, suited for calls that usually take milliseconds but can stall during failovers.
+var DefaultLatencyBuckets = []float64{.005, .01, .025, .05, .1, .25, .5, 1, 2.5, 5, 10}
+
+// Gets the API a call belongs to.
+func callAPI(call interface{}) string {
+	switch call.(type) {
+	case *mesos_v1_scheduler.Call:
+		return "scheduler"
+	case *mesos_v1_executor.Call:
+		return "executor"
+	}
+
+	return "unknown"
+}
+
+// Creates a new set of metrics using the default latency buckets.
+func NewMetrics() *Metrics {
+	return NewMetricsWithBuckets(DefaultLatencyBuckets)
+}
+
+// Creates a new set of metrics with custom latency buckets, given in seconds.
+func NewMetricsWithBuckets(buckets []float64) *Metrics {
+	b := make([]float64, len(buckets))
+	copy(b, buckets)
+	sort.Float64s(b)
+
+	return &Metrics{
+		buckets: b,
+		series:  make(map[metricsKey]*metricsSeries),
+	}
+}
+
+// Metrics don't need anything from the context.
+func (m *Metrics) Begin(ctx context.Context, o *Observation) context.Context {
+	return ctx
+}
+
+// Records the outcome of a call.
+func (m *Metrics) End(ctx context.Context, o *Observation) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	key := metricsKey{api: o.API, call: o.Call}
+	s, ok := m.series[key]
+	if !ok {
+		s = &metricsSeries{
+			codes:   make(map[int]uint64),
+			buckets: make([]uint64, len(m.buckets)),
+		}
+		m.series[key] = s
+	}
+
+	seconds := o.Duration.Seconds()
+	for i, b := range m.buckets {
+		if seconds <= b {
+			s.buckets[i]++
+		}
+	}
+
+	s.codes[o.StatusCode]++
+	s.requestBytes += uint64(o.RequestBytes)
+	s.sum += seconds
+	s.count++
+	if o.Err != nil {
+		s.errors++
+	}
+}
+
+// Writes out all metrics in the Prometheus text exposition format.
+func (m *Metrics) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4")
+	w.Write(m.expose())
+}
+
+// Renders the metrics, sorted so that the output is stable between scrapes.
+func (m *Metrics) expose() []byte {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	keys := make([]metricsKey, 0, len(m.series))
+	for k := range m.series {
+		keys = append(keys, k)
+	}
+	sort.Sort(metricsKeys(keys))
+
+	var buf bytes.Buffer
+
+	buf.WriteString("# HELP mesos_client_requests_total Calls made to Mesos by response status code.\n")
+	buf.WriteString("# TYPE mesos_client_requests_total counter\n")
+	for _, k := range keys {
+		s := m.series[k]
+		codes := make([]int, 0, len(s.codes))
+		for c := range s.codes {
+			codes = append(codes, c)
+		}
+		sort.Ints(codes)
+
+		for _, c := range codes {
+			code := "none"
+			if c != 0 {
+				code = strconv.Itoa(c)
+			}
+			writeSample(&buf, "mesos_client_requests_total", k, `,code="`+code+`"`, float64(s.codes[c]))
+		}
+	}
+
+	buf.WriteString("# HELP mesos_client_request_errors_total Calls to Mesos that failed.\n")
+	buf.WriteString("# TYPE mesos_client_request_errors_total counter\n")
+	for _, k := range keys {
+		writeSample(&buf, "mesos_client_request_errors_total", k, "", float64(m.series[k].errors))
+	}
+
+	buf.WriteString("# HELP mesos_client_request_bytes_total Size of the encoded calls sent to Mesos.\n")
+	buf.WriteString("# TYPE mesos_client_request_bytes_total counter\n")
+	for _, k := range keys {
+		writeSample(&buf, "mesos_client_request_bytes_total", k, "", float64(m.series[k].requestBytes))
+	}
+
+	buf.WriteString("# HELP mesos_client_request_duration_seconds Time taken by calls to Mesos, including retries.\n")
+	buf.WriteString("# TYPE mesos_client_request_duration_seconds histogram\n")
+	for _, k := range keys {
+		s := m.series[k]
+		for i, b := range m.buckets {
+			le := strconv.FormatFloat(b, 'g', -1, 64)
+			writeSample(&buf, "mesos_client_request_duration_seconds_bucket", k, `,le="`+le+`"`, float64(s.buckets[i]))
+		}
+		writeSample(&buf, "mesos_client_request_duration_seconds_bucket", k, `,le="+Inf"`, float64(s.count))
+		writeSample(&buf, "mesos_client_request_duration_seconds_sum", k, "", s.sum)
+		writeSample(&buf, "mesos_client_request_duration_seconds_count", k, "", float64(s.count))
+	}
+
+	return buf.Bytes()
+}
+
+// Writes a single sample line with the API and call labels plus any extra ones.
+func writeSample(buf *bytes.Buffer, name string, k metricsKey, extra string, value float64) {
+	buf.WriteString(name)
+	buf.WriteString(`{api="` + k.api + `",call="` + k.call + `"` + extra + "} ")
+	buf.WriteString(strconv.FormatFloat(value, 'g', -1, 64))
+	buf.WriteByte('\n')
+}
+
+type metricsKeys []metricsKey
+
+func (k metricsKeys) Len() int {
+	return len(k)
+}
+
+func (k metricsKeys) Swap(i, j int) {
+	k[i], k[j] = k[j], k[i]
+}
+
+func (k metricsKeys) Less(i, j int) bool {
+	if k[i].api != k[j].api {
+		return k[i].api < k[j].api
+	}
+
+	return k[i].call < k[j].call
+}
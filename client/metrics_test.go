@@ -0,0 +1,138 @@
+// Copyright 2017 Verizon
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package client
+
+import (
+	"context"
+	"github.com/verizonlabs/mesos-framework-sdk/include/mesos_v1_executor"
+	"github.com/verizonlabs/mesos-framework-sdk/include/mesos_v1_scheduler"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+type traceKey struct{}
+
+// Records observations and tags the context like a tracer would.
+type mockInstrumentation struct {
+	observed []Observation
+	traced   bool
+}
+
+func (m *mockInstrumentation) Begin(ctx context.Context, o *Observation) context.Context {
+	return context.WithValue(ctx, traceKey{}, o.Call)
+}
+
+func (m *mockInstrumentation) End(ctx context.Context, o *Observation) {
+	m.traced = ctx.Value(traceKey{}) == o.Call
+	m.observed = append(m.observed, *o)
+}
+
+// Makes sure every call is reported with its type, status and size.
+func TestDefaultClient_RequestInstrumentation(t *testing.T) {
+	t.Parallel()
+
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusAccepted)
+	}))
+	defer ts.Close()
+
+	inst := new(mockInstrumentation)
+	c := NewClient(ClientData{Endpoint: ts.URL, Instrumentation: inst}, l)
+
+	_, err := c.Request(&mesos_v1_scheduler.Call{Type: mesos_v1_scheduler.Call_ACCEPT.Enum()})
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	if len(inst.observed) != 1 || !inst.traced {
+		t.Fatal("Call was not observed with the context from Begin")
+	}
+
+	o := inst.observed[0]
+	if o.API != "scheduler" || o.Call != "ACCEPT" || o.StatusCode != http.StatusAccepted || o.RequestBytes == 0 || o.Attempts != 1 {
+		t.Fatalf("Observation is wrong: %+v", o)
+	}
+}
+
+// Checks that our exposition handler reports what the client observed.
+func TestMetrics_ServeHTTP(t *testing.T) {
+	t.Parallel()
+
+	m := NewMetricsWithBuckets([]float64{1, 0.1})
+	m.End(context.Background(), &Observation{
+		API:          "scheduler",
+		Call:         "ACKNOWLEDGE",
+		Duration:     50 * time.Millisecond,
+		StatusCode:   http.StatusAccepted,
+		RequestBytes: 10,
+	})
+	m.End(context.Background(), &Observation{
+		API:      "executor",
+		Call:     "UPDATE",
+		Duration: 2 * time.Second,
+		Err:      context.DeadlineExceeded,
+	})
+
+	ts := httptest.NewServer(m)
+	defer ts.Close()
+
+	resp, err := http.Get(ts.URL)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	defer resp.Body.Close()
+
+	body, _ := ioutil.ReadAll(resp.Body)
+	expected := []string{
+		`mesos_client_requests_total{api="scheduler",call="ACKNOWLEDGE",code="202"} 1`,
+		`mesos_client_requests_total{api="executor",call="UPDATE",code="none"} 1`,
+		`mesos_client_request_errors_total{api="executor",call="UPDATE"} 1`,
+		`mesos_client_request_bytes_total{api="scheduler",call="ACKNOWLEDGE"} 10`,
+		`mesos_client_request_duration_seconds_bucket{api="scheduler",call="ACKNOWLEDGE",le="0.1"} 1`,
+		`mesos_client_request_duration_seconds_bucket{api="executor",call="UPDATE",le="1"} 0`,
+		`mesos_client_request_duration_seconds_bucket{api="executor",call="UPDATE",le="+Inf"} 1`,
+		`mesos_client_request_duration_seconds_count{api="executor",call="UPDATE"} 1`,
+	}
+	for _, e := range expected {
+		if !strings.Contains(string(body), e) {
+			t.Fatalf("Missing %s in:\n%s", e, body)
+		}
+	}
+}
+
+// Ensures executor calls are labeled separately from scheduler calls.
+func TestCallAPI(t *testing.T) {
+	t.Parallel()
+
+	if callAPI(&mesos_v1_scheduler.Call{}) != "scheduler" || callAPI(&mesos_v1_executor.Call{}) != "executor" {
+		t.Fatal("Calls were not labeled with the right API")
+	}
+}
+
+// Measures performance of recording a call.
+func BenchmarkMetrics_End(b *testing.B) {
+	m := NewMetrics()
+	o := &Observation{API: "scheduler", Call: "ACCEPT", Duration: time.Millisecond, StatusCode: http.StatusAccepted}
+	ctx := context.Background()
+	b.ResetTimer()
+
+	for n := 0; n < b.N; n++ {
+		m.End(ctx, o)
+	}
+}
@@ -0,0 +1,136 @@
+// Copyright 2017 Verizon
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package client
+
+import (
+	"github.com/verizonlabs/mesos-framework-sdk/include/mesos_v1_executor"
+	"github.com/verizonlabs/mesos-framework-sdk/include/mesos_v1_scheduler"
+	"math/rand"
+	"net/http"
+	"sync"
+	"time"
+)
+
+type (
+
+	// Decides if and when a failed call should be attempted again.
+	// Attempts are numbered starting at 1.
+	RetryPolicy interface {
+		Retry(call interface{}, attempt int, resp *http.Response, err error) (time.Duration, bool)
+	}
+
+	// Observes each attempt at making a call, such as for logging or metrics.
+	AttemptHook func(Attempt)
+
+	// Describes the outcome of a single attempt at making a call.
+	Attempt struct {
+		Number   int
+		Call     interface{}
+		Response *http.Response
+		Err      error
+		Retry    bool          // Whether the call will be attempted again.
+		Delay    time.Duration // How long we wait before the next attempt.
+	}
+
+	// Retries transient failures with exponentially increasing delays.
+	// Calls that aren't idempotent are only retried if they never reached the master.
+	ExponentialBackoff struct {
+		MaxAttempts int
+		Initial     time.Duration
+		Max         time.Duration
+		Jitter      float64                     // Fraction of each delay that is randomized, between 0 and 1.
+		Idempotent  func(call interface{}) bool // Defaults to IsIdempotent.
+		rand        *rand.Rand
+		lock        sync.Mutex
+	}
+)
+
+// Creates an exponential backoff policy with a moderate amount of jitter.
+func NewExponentialBackoff(maxAttempts int, initial, max time.Duration) *ExponentialBackoff {
+	return &ExponentialBackoff{
+		MaxAttempts: maxAttempts,
+		Initial:     initial,
+		Max:         max,
+		Jitter:      0.2,
+		Idempotent:  IsIdempotent,
+	}
+}
+
+// Determines how long to wait before the next attempt, if there should be one at all.
+func (e *ExponentialBackoff) Retry(call interface{}, attempt int, resp *http.Response, err error) (time.Duration, bool) {
+	if attempt >= e.MaxAttempts || !IsRetryable(err) {
+		return 0, false
+	}
+
+	idempotent := e.Idempotent
+	if idempotent == nil {
+		idempotent = IsIdempotent
+	}
+
+	// We can't tell if the master acted on the call, so don't risk doing it twice.
+	if !idempotent(call) && !isDialError(err) {
+		return 0, false
+	}
+
+	delay := e.Initial
+	for i := 1; i < attempt && delay < e.Max; i++ {
+		delay *= 2
+	}
+
+	if delay > e.Max {
+		delay = e.Max
+	}
+
+	if e.Jitter > 0 {
+		e.lock.Lock()
+		if e.rand == nil {
+			e.rand = rand.New(rand.NewSource(time.Now().UnixNano()))
+		}
+		delay -= time.Duration(e.rand.Float64() * e.Jitter * float64(delay))
+		e.lock.Unlock()
+	}
+
+	return delay, true
+}
+
+// Tells us if a call can safely be sent more than once.
+// Accepting offers and sending framework messages can act twice if repeated.
+func IsIdempotent(call interface{}) bool {
+	switch call := call.(type) {
+	case *mesos_v1_scheduler.Call:
+		switch call.GetType() {
+		case mesos_v1_scheduler.Call_ACCEPT, mesos_v1_scheduler.Call_MESSAGE:
+			return false
+		}
+	case *mesos_v1_executor.Call:
+		if call.GetType() == mesos_v1_executor.Call_MESSAGE {
+			return false
+		}
+	}
+
+	return true
+}
+
+// Gets the name of the call's type for logging.
+func callType(call interface{}) string {
+	switch call := call.(type) {
+	case *mesos_v1_scheduler.Call:
+		return call.GetType().String()
+	case *mesos_v1_executor.Call:
+		return call.GetType().String()
+	}
+
+	return "UNKNOWN"
+}
@@ -0,0 +1,119 @@
+// Copyright 2017 Verizon
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package client
+
+import (
+	"github.com/verizonlabs/mesos-framework-sdk/include/mesos_v1_scheduler"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// Checks that delays grow exponentially and stop at the maximum.
+func TestExponentialBackoff_Retry(t *testing.T) {
+	t.Parallel()
+
+	b := NewExponentialBackoff(5, time.Second, 3*time.Second)
+	b.Jitter = 0
+	call := &mesos_v1_scheduler.Call{Type: mesos_v1_scheduler.Call_DECLINE.Enum()}
+	resp := &http.Response{StatusCode: http.StatusServiceUnavailable}
+	err := newError(resp, "DECLINE", "master", "No leader")
+
+	expected := []time.Duration{time.Second, 2 * time.Second, 3 * time.Second, 3 * time.Second}
+	for i, e := range expected {
+		delay, retry := b.Retry(call, i+1, resp, err)
+		if !retry || delay != e {
+			t.Fatalf("Attempt %d should be retried after %v, got %v", i+1, e, delay)
+		}
+	}
+
+	if _, retry := b.Retry(call, 5, resp, err); retry {
+		t.Fatal("Maximum number of attempts was not respected")
+	}
+
+	resp = &http.Response{StatusCode: http.StatusBadRequest}
+	if _, retry := b.Retry(call, 1, resp, newError(resp, "DECLINE", "master", "")); retry {
+		t.Fatal("Bad requests should never be retried")
+	}
+}
+
+// Makes sure that accepts are only retried when they never reached the master.
+func TestExponentialBackoff_RetryAccept(t *testing.T) {
+	t.Parallel()
+
+	b := NewExponentialBackoff(3, time.Millisecond, time.Millisecond)
+	accept := &mesos_v1_scheduler.Call{Type: mesos_v1_scheduler.Call_ACCEPT.Enum()}
+
+	resp := &http.Response{StatusCode: http.StatusServiceUnavailable}
+	if _, retry := b.Retry(accept, 1, resp, newError(resp, "ACCEPT", "master", "")); retry {
+		t.Fatal("Accept calls should not be retried blindly")
+	}
+
+	down := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	down.Close()
+	_, err := http.Get(down.URL)
+
+	if _, retry := b.Retry(accept, 1, nil, err); !retry {
+		t.Fatal("Accept calls that were never sent should be retried")
+	}
+}
+
+// Measures performance of our backoff calculations.
+func BenchmarkExponentialBackoff_Retry(b *testing.B) {
+	p := NewExponentialBackoff(10, time.Second, time.Minute)
+	call := &mesos_v1_scheduler.Call{}
+	resp := &http.Response{StatusCode: http.StatusServiceUnavailable}
+	err := newError(resp, "DECLINE", "master", "No leader")
+	b.ResetTimer()
+
+	for n := 0; n < b.N; n++ {
+		p.Retry(call, n%10, resp, err)
+	}
+}
+
+// Ensures the client retries transient failures and reports every attempt.
+func TestDefaultClient_RequestRetry(t *testing.T) {
+	t.Parallel()
+
+	var requests int32
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&requests, 1) < 3 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer ts.Close()
+
+	var attempts []Attempt
+	c := NewClient(ClientData{
+		Endpoint: ts.URL,
+		Retry:    NewExponentialBackoff(3, time.Millisecond, time.Millisecond),
+		OnAttempt: func(a Attempt) {
+			attempts = append(attempts, a)
+		},
+	}, l)
+
+	_, err := c.Request(&mesos_v1_scheduler.Call{})
+	if err != nil {
+		t.Fatal("Request should have succeeded after retrying: " + err.Error())
+	}
+
+	if len(attempts) != 3 || !attempts[0].Retry || attempts[2].Err != nil {
+		t.Fatalf("Expected 3 attempts to be observed, got %d", len(attempts))
+	}
+}
@@ -0,0 +1,193 @@
+// Copyright 2017 Verizon
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package client
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"github.com/verizonlabs/mesos-framework-sdk/logging"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"os"
+	"sync"
+	"time"
+)
+
+// How often we check our certificates on disk for changes.
+const tlsReloadInterval = 30 * time.Second
+
+type (
+
+	// TLS settings for connections to masters and agents.
+	// Setting both CertFile and KeyFile presents a client certificate for mutual TLS.
+	TLSConfig struct {
+		CAFile     string // PEM bundle used instead of the system roots to verify the server.
+		CertFile   string
+		KeyFile    string
+		ServerName string // Overrides the name used for SNI and verification.
+		MinVersion uint16 // Defaults to TLS 1.2.
+	}
+
+	// Wraps a TLS enabled transport and rebuilds it whenever our certificates are rotated on disk.
+	tlsTransport struct {
+		cfg       *TLSConfig
+		dial      func(network, addr string) (net.Conn, error)
+		logger    logging.Logger
+		transport *http.Transport
+		err       error
+		modTimes  map[string]time.Time
+		checked   time.Time
+		interval  time.Duration
+		lock      sync.Mutex
+	}
+)
+
+// Creates a new TLS transport. Problems loading certificates are returned from each request until they're fixed.
+func newTLSTransport(cfg *TLSConfig, dial func(network, addr string) (net.Conn, error), logger logging.Logger) *tlsTransport {
+	t := &tlsTransport{
+		cfg:      cfg,
+		dial:     dial,
+		logger:   logger,
+		interval: tlsReloadInterval,
+		checked:  time.Now(),
+	}
+	t.reload()
+
+	return t
+}
+
+// Sends the request over the most recently loaded transport.
+func (t *tlsTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	transport, err := t.current()
+	if err != nil {
+		return nil, err
+	}
+
+	return transport.RoundTrip(req)
+}
+
+// Gets the current transport, reloading our certificates first if they've changed.
+func (t *tlsTransport) current() (*http.Transport, error) {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	if time.Since(t.checked) >= t.interval {
+		t.checked = time.Now()
+		if t.changed() {
+			t.reload()
+		}
+	}
+
+	return t.transport, t.err
+}
+
+// Tells us if any of our certificate files have been modified since we last loaded them.
+func (t *tlsTransport) changed() bool {
+	for _, file := range t.files() {
+		info, err := os.Stat(file)
+		if err != nil || !info.ModTime().Equal(t.modTimes[file]) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Lists the files that make up our TLS configuration.
+func (t *tlsTransport) files() []string {
+	files := make([]string, 0, 3)
+	for _, file := range []string{t.cfg.CAFile, t.cfg.CertFile, t.cfg.KeyFile} {
+		if file != "" {
+			files = append(files, file)
+		}
+	}
+
+	return files
+}
+
+// Loads our certificates and swaps in a new transport that uses them.
+// If a rotation is only partially written we keep using the old transport and try again later.
+func (t *tlsTransport) reload() {
+	modTimes := make(map[string]time.Time, 3)
+	for _, file := range t.files() {
+		if info, err := os.Stat(file); err == nil {
+			modTimes[file] = info.ModTime()
+		}
+	}
+
+	cfg, err := t.config()
+	if err != nil {
+		t.logger.Emit(logging.ERROR, "Failed to load TLS configuration: %s", err.Error())
+		if t.transport == nil {
+			t.err = err
+		}
+
+		return
+	}
+
+	old := t.transport
+	t.transport = &http.Transport{
+		Dial:            t.dial,
+		TLSClientConfig: cfg,
+	}
+	t.err = nil
+	t.modTimes = modTimes
+
+	if old != nil {
+		old.CloseIdleConnections()
+		t.logger.Emit(logging.INFO, "Reloaded TLS certificates")
+	}
+}
+
+// Builds the TLS configuration from the files on disk.
+func (t *tlsTransport) config() (*tls.Config, error) {
+	cfg := &tls.Config{
+		ServerName: t.cfg.ServerName,
+		MinVersion: t.cfg.MinVersion,
+	}
+
+	if cfg.MinVersion == 0 {
+		cfg.MinVersion = tls.VersionTLS12
+	}
+
+	if t.cfg.CAFile != "" {
+		ca, err := ioutil.ReadFile(t.cfg.CAFile)
+		if err != nil {
+			return nil, err
+		}
+
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(ca) {
+			return nil, errors.New("No certificates found in CA file " + t.cfg.CAFile)
+		}
+		cfg.RootCAs = pool
+	}
+
+	if (t.cfg.CertFile == "") != (t.cfg.KeyFile == "") {
+		return nil, errors.New("Both a client certificate and key are required for mutual TLS")
+	}
+
+	if t.cfg.CertFile != "" {
+		cert, err := tls.LoadX509KeyPair(t.cfg.CertFile, t.cfg.KeyFile)
+		if err != nil {
+			return nil, err
+		}
+		cfg.Certificates = []tls.Certificate{cert}
+	}
+
+	return cfg, nil
+}
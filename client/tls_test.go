@@ -0,0 +1,168 @@
+// Copyright 2017 Verizon
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package client
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"github.com/verizonlabs/mesos-framework-sdk/include/mesos_v1_scheduler"
+	"io/ioutil"
+	"math/big"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// Generates a self-signed certificate and key for localhost and writes them out as PEM files.
+func writeCertificate(t *testing.T, dir, name string) (string, string, *x509.Certificate) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(time.Now().UnixNano()),
+		Subject:               pkix.Name{CommonName: name},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		KeyUsage:              x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+		ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth, x509.ExtKeyUsageClientAuth},
+		IPAddresses:           []net.IP{net.ParseIP("127.0.0.1")},
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	keyDer, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	certFile := filepath.Join(dir, name+".crt")
+	keyFile := filepath.Join(dir, name+".key")
+	ioutil.WriteFile(certFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0600)
+	ioutil.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDer}), 0600)
+
+	cert, _ := x509.ParseCertificate(der)
+
+	return certFile, keyFile, cert
+}
+
+// Makes sure we can talk to a master that requires mutual TLS, and that rotated client certificates are picked up.
+func TestDefaultClient_RequestMutualTLS(t *testing.T) {
+	t.Parallel()
+
+	dir, err := ioutil.TempDir("", "tls")
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	defer os.RemoveAll(dir)
+
+	serverCert, serverKey, _ := writeCertificate(t, dir, "server")
+	trustedCert, trustedKey, trusted := writeCertificate(t, dir, "trusted")
+	clientCert, clientKey, _ := writeCertificate(t, dir, "client")
+
+	pool := x509.NewCertPool()
+	pool.AddCert(trusted)
+	keyPair, err := tls.LoadX509KeyPair(serverCert, serverKey)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	ts := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	ts.TLS = &tls.Config{
+		Certificates: []tls.Certificate{keyPair},
+		ClientCAs:    pool,
+		ClientAuth:   tls.RequireAndVerifyClientCert,
+	}
+	ts.StartTLS()
+	defer ts.Close()
+
+	c := NewClient(ClientData{
+		Endpoint: ts.URL,
+		TLS: &TLSConfig{
+			CAFile:   serverCert,
+			CertFile: clientCert,
+			KeyFile:  clientKey,
+		},
+	}, l)
+
+	_, err = c.Request(&mesos_v1_scheduler.Call{})
+	if err == nil {
+		t.Fatal("Untrusted client certificate should have been rejected")
+	}
+
+	// Rotate in the trusted certificate and check for changes on every request.
+	data, _ := ioutil.ReadFile(trustedCert)
+	ioutil.WriteFile(clientCert, data, 0600)
+	data, _ = ioutil.ReadFile(trustedKey)
+	ioutil.WriteFile(clientKey, data, 0600)
+	future := time.Now().Add(time.Minute)
+	os.Chtimes(clientCert, future, future)
+	os.Chtimes(clientKey, future, future)
+	c.(*DefaultClient).client.Transport.(*tlsTransport).interval = 0
+
+	_, err = c.Request(&mesos_v1_scheduler.Call{})
+	if err != nil {
+		t.Fatal("Rotated client certificate should have been used: " + err.Error())
+	}
+}
+
+// Ensures that a broken TLS configuration fails every request instead of falling back to something insecure.
+func TestDefaultClient_RequestBadTLS(t *testing.T) {
+	t.Parallel()
+
+	ts := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer ts.Close()
+
+	c := NewClient(ClientData{
+		Endpoint: ts.URL,
+		TLS: &TLSConfig{
+			CAFile: "/does/not/exist",
+		},
+	}, l)
+
+	_, err := c.Request(&mesos_v1_scheduler.Call{})
+	if err == nil {
+		t.Fatal("Request should have failed without a CA")
+	}
+
+	c = NewClient(ClientData{
+		Endpoint: ts.URL,
+		TLS:      &TLSConfig{},
+	}, l)
+
+	_, err = c.Request(&mesos_v1_scheduler.Call{})
+	if err == nil {
+		t.Fatal("Server certificate should not have been trusted")
+	}
+}
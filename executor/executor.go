@@ -15,6 +15,7 @@
 package executor
 
 import (
+	"context"
 	"github.com/verizonlabs/mesos-framework-sdk/client"
 	"github.com/verizonlabs/mesos-framework-sdk/include/mesos_v1"
 	exec "github.com/verizonlabs/mesos-framework-sdk/include/mesos_v1_executor"
@@ -26,6 +27,9 @@ type Executor interface {
 	Subscribe(chan *exec.Event) error
 	Update(*mesos_v1.TaskStatus) error
 	Message([]byte) error
+	SubscribeContext(context.Context, chan *exec.Event) error
+	UpdateContext(context.Context, *mesos_v1.TaskStatus) error
+	MessageContext(context.Context, []byte) error
 }
 
 type DefaultExecutor struct {
@@ -85,6 +89,11 @@ func (e *DefaultExecutor) unacknowledgedUpdates() []*exec.Call_Update {
 }
 
 func (e *DefaultExecutor) Subscribe(eventChan chan *exec.Event) error {
+	return e.SubscribeContext(context.Background(), eventChan)
+}
+
+// The subscription stream is closed as soon as the context is done.
+func (e *DefaultExecutor) SubscribeContext(ctx context.Context, eventChan chan *exec.Event) error {
 	subscribe := &exec.Call{
 		FrameworkId: e.frameworkId,
 		ExecutorId:  e.executorId,
@@ -95,15 +104,19 @@ func (e *DefaultExecutor) Subscribe(eventChan chan *exec.Event) error {
 		},
 	}
 
-	resp, err := e.client.Request(subscribe)
+	resp, err := client.RequestContext(ctx, e.client, subscribe)
 	if err != nil {
 		return err
 	} else {
-		return recordio.Decode(resp.Body, eventChan)
+		return recordio.DecodeWithCodec(ctx, recordio.CodecFor(resp.Header), resp.Body, eventChan)
 	}
 }
 
 func (e *DefaultExecutor) Update(taskStatus *mesos_v1.TaskStatus) error {
+	return e.UpdateContext(context.Background(), taskStatus)
+}
+
+func (e *DefaultExecutor) UpdateContext(ctx context.Context, taskStatus *mesos_v1.TaskStatus) error {
 	update := &exec.Call{
 		FrameworkId: e.frameworkId,
 		ExecutorId:  e.executorId,
@@ -112,12 +125,16 @@ func (e *DefaultExecutor) Update(taskStatus *mesos_v1.TaskStatus) error {
 			Status: taskStatus,
 		},
 	}
-	_, err := e.client.Request(update)
+	_, err := client.RequestContext(ctx, e.client, update)
 
 	return err
 }
 
 func (e *DefaultExecutor) Message(data []byte) error {
+	return e.MessageContext(context.Background(), data)
+}
+
+func (e *DefaultExecutor) MessageContext(ctx context.Context, data []byte) error {
 	message := &exec.Call{
 		FrameworkId: e.frameworkId,
 		ExecutorId:  e.executorId,
@@ -126,7 +143,7 @@ func (e *DefaultExecutor) Message(data []byte) error {
 			Data: data,
 		},
 	}
-	_, err := e.client.Request(message)
+	_, err := client.RequestContext(ctx, e.client, message)
 
 	return err
 }
@@ -0,0 +1,93 @@
+// Copyright 2017 Verizon
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package recordio
+
+import (
+	"bytes"
+	"net/http"
+	"strings"
+
+	"github.com/golang/protobuf/jsonpb"
+	"github.com/golang/protobuf/proto"
+)
+
+// Codec defines the wire format used for calls and events.
+type Codec interface {
+	ContentType() string
+	Marshal(proto.Message) ([]byte, error)
+	Unmarshal([]byte, proto.Message) error
+}
+
+type (
+	protobufCodec struct{}
+	jsonCodec     struct{}
+)
+
+var (
+	// Protobuf is the default and most efficient wire format.
+	Protobuf Codec = protobufCodec{}
+
+	// JSON is human readable which makes it useful for debugging and capturing traffic.
+	JSON Codec = jsonCodec{}
+)
+
+func (protobufCodec) ContentType() string {
+	return "application/x-protobuf"
+}
+
+func (protobufCodec) Marshal(msg proto.Message) ([]byte, error) {
+	return proto.Marshal(msg)
+}
+
+func (protobufCodec) Unmarshal(data []byte, msg proto.Message) error {
+	return proto.Unmarshal(data, msg)
+}
+
+func (jsonCodec) ContentType() string {
+	return "application/json"
+}
+
+// Mesos expects the original snake case field names from the protobuf definitions.
+func (jsonCodec) Marshal(msg proto.Message) ([]byte, error) {
+	var buf bytes.Buffer
+	marshaler := jsonpb.Marshaler{OrigName: true}
+	if err := marshaler.Marshal(&buf, msg); err != nil {
+		return nil, err
+	}
+
+	return buf.Bytes(), nil
+}
+
+// Fields from newer versions of Mesos are ignored rather than failing the whole event.
+func (jsonCodec) Unmarshal(data []byte, msg proto.Message) error {
+	unmarshaler := jsonpb.Unmarshaler{AllowUnknownFields: true}
+
+	return unmarshaler.Unmarshal(bytes.NewReader(data), msg)
+}
+
+// CodecFor picks the codec matching the content type of a response.
+// Streams may describe their records with Message-Content-Type, otherwise Content-Type is used.
+func CodecFor(header http.Header) Codec {
+	contentType := header.Get("Message-Content-Type")
+	if contentType == "" {
+		contentType = header.Get("Content-Type")
+	}
+
+	if strings.Contains(contentType, "json") {
+		return JSON
+	}
+
+	return Protobuf
+}
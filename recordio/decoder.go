@@ -16,23 +16,50 @@ package recordio
 
 import (
 	"bufio"
+	"context"
 	"errors"
-	"io"
 	"github.com/verizonlabs/mesos-framework-sdk/include/mesos_v1_executor"
 	"github.com/verizonlabs/mesos-framework-sdk/include/mesos_v1_scheduler"
+	"io"
 	"strconv"
 	"strings"
-
-	"github.com/golang/protobuf/proto"
 )
 
 // Decode continually reads and constructs events from the Mesos stream.
 func Decode(data io.ReadCloser, events interface{}) error {
+	return DecodeContext(context.Background(), data, events)
+}
+
+// DecodeContext is the same as Decode but stops as soon as the context is done.
+// The stream is closed on cancellation to unblock any pending read and the context's error is returned.
+func DecodeContext(ctx context.Context, data io.ReadCloser, events interface{}) error {
+	return DecodeWithCodec(ctx, Protobuf, data, events)
+}
+
+// DecodeWithCodec is the same as DecodeContext but events are decoded with the given codec.
+func DecodeWithCodec(ctx context.Context, codec Codec, data io.ReadCloser, events interface{}) error {
+	if ctx.Done() != nil {
+		finished := make(chan struct{})
+		defer close(finished)
+
+		go func() {
+			select {
+			case <-ctx.Done():
+				data.Close()
+			case <-finished:
+			}
+		}()
+	}
+
 	reader := bufio.NewReader(data)
 
 	for {
 		lengthStr, err := reader.ReadString('\n')
 		if err != nil {
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+
 			return err
 		}
 
@@ -44,26 +71,38 @@ func Decode(data io.ReadCloser, events interface{}) error {
 		buffer := make([]byte, lengthInt)
 		n, err := io.ReadFull(reader, buffer)
 		if n != lengthInt {
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+
 			return errors.New("Amount of bytes read does not match the RecordIO message length")
 		}
 
 		switch events := events.(type) {
 		case chan *mesos_v1_scheduler.Event:
 			var event mesos_v1_scheduler.Event
-			err := proto.Unmarshal(buffer, &event)
+			err := codec.Unmarshal(buffer, &event)
 			if err != nil {
 				return errors.New("Failed to decode event: " + err.Error())
 			}
 
-			events <- &event
+			select {
+			case events <- &event:
+			case <-ctx.Done():
+				return ctx.Err()
+			}
 		case chan *mesos_v1_executor.Event:
 			var event mesos_v1_executor.Event
-			err := proto.Unmarshal(buffer, &event)
+			err := codec.Unmarshal(buffer, &event)
 			if err != nil {
 				return errors.New("Failed to decode event: " + err.Error())
 			}
 
-			events <- &event
+			select {
+			case events <- &event:
+			case <-ctx.Done():
+				return ctx.Err()
+			}
 		}
 	}
 }
@@ -0,0 +1,449 @@
+// Copyright 2017 Verizon
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package resources
+
+// Arithmetic on lists of resources, such as working out what's left of an offer after launching a task.
+import (
+	"errors"
+	"github.com/golang/protobuf/proto"
+	"github.com/verizonlabs/mesos-framework-sdk/include/mesos_v1"
+	"github.com/verizonlabs/mesos-framework-sdk/utils"
+	"math"
+	"strconv"
+)
+
+// Mesos only keeps three decimal places for scalars.
+const scalarEpsilon = 0.0005
+
+// Creates a resource made up of ranges, such as ports.
+// Ranges starting at 0 ask for that many values wherever they're available when allocated,
+// so a range from 0 to 2 asks for any three values.
+func CreateRangesResource(name, role string, ranges ...*mesos_v1.Value_Range) *mesos_v1.Resource {
+	resource := &mesos_v1.Resource{
+		Name:   utils.ProtoString(name),
+		Type:   mesos_v1.Value_RANGES.Enum(),
+		Ranges: &mesos_v1.Value_Ranges{Range: ranges},
+	}
+
+	if role != "" {
+		resource.Role = utils.ProtoString(role)
+	}
+
+	return resource
+}
+
+// Creates a range covering begin through end.
+func CreateRange(begin, end uint64) *mesos_v1.Value_Range {
+	return &mesos_v1.Value_Range{
+		Begin: utils.ProtoUint64(begin),
+		End:   utils.ProtoUint64(end),
+	}
+}
+
+// Creates a ports resource, where a port of 0 asks for any port.
+// Every port of 0 is merged into a single range asking for that many ports.
+func CreatePortsResource(role string, ports ...uint64) *mesos_v1.Resource {
+	ranges := make([]*mesos_v1.Value_Range, 0, len(ports))
+	var count uint64
+	for _, port := range ports {
+		if port == 0 {
+			count++
+			continue
+		}
+		ranges = append(ranges, CreateRange(port, port))
+	}
+
+	if count > 0 {
+		ranges = append(ranges, CreateRange(0, count-1))
+	}
+
+	return CreateRangesResource("ports", role, ranges...)
+}
+
+// Creates a resource made up of a set of items.
+func CreateSetResource(name, role string, items ...string) *mesos_v1.Resource {
+	resource := &mesos_v1.Resource{
+		Name: utils.ProtoString(name),
+		Type: mesos_v1.Value_SET.Enum(),
+		Set:  &mesos_v1.Value_Set{Item: items},
+	}
+
+	if role != "" {
+		resource.Role = utils.ProtoString(role)
+	}
+
+	return resource
+}
+
+/*
+Allocate works out exactly which of the available resources satisfy what's wanted, without touching either list.
+
+Allocated resources are copies of the available ones they came from, so they carry the same role, reservation,
+allocation and disk info, which is what Mesos expects to see when launching. Ranges asked for by count are turned
+into the exact values handed out, lowest first, once every explicitly wanted range has been taken.
+Wanted resources with a role other than "*" only come out of resources for that role, and persistent volumes only
+come out of the volume with the same persistence ID.
+What's left over is handed back as well, with anything that ran out dropped.
+*/
+func Allocate(available, wanted []*mesos_v1.Resource) (allocated, remaining []*mesos_v1.Resource, err error) {
+	all, remaining, err := AllocateAll(available, [][]*mesos_v1.Resource{wanted})
+	if err != nil {
+		return nil, remaining, err
+	}
+
+	return all[0], remaining, nil
+}
+
+// Allocates several lists of wanted resources out of the same available ones, such as for every task sharing an offer.
+// Explicit ranges are taken for all of them before any ranges asked for by count,
+// so the ports picked for one list never take away a port that another one asked for by number.
+func AllocateAll(available []*mesos_v1.Resource, wanted [][]*mesos_v1.Resource) (allocated [][]*mesos_v1.Resource, remaining []*mesos_v1.Resource, err error) {
+	remaining = make([]*mesos_v1.Resource, 0, len(available))
+	for _, r := range available {
+		remaining = append(remaining, proto.Clone(r).(*mesos_v1.Resource))
+	}
+
+	type counted struct {
+		i int
+		w *mesos_v1.Resource
+	}
+	var later []counted
+
+	allocated = make([][]*mesos_v1.Resource, len(wanted))
+	for i, list := range wanted {
+		for _, w := range list {
+			if w.GetType() == mesos_v1.Value_RANGES {
+				explicit, count := splitRanges(w)
+				if count != nil {
+					later = append(later, counted{i, count})
+				}
+				if explicit == nil {
+					continue
+				}
+				w = explicit
+			}
+
+			taken, err := take(remaining, w)
+			if err != nil {
+				return nil, available, err
+			}
+			allocated[i] = append(allocated[i], taken...)
+		}
+	}
+
+	for _, c := range later {
+		taken, err := takeRanges(remaining, c.w)
+		if err != nil {
+			return nil, available, err
+		}
+		allocated[c.i] = append(allocated[c.i], taken...)
+	}
+
+	return allocated, prune(remaining), nil
+}
+
+// Tells us if the available resources can satisfy everything that's wanted.
+func Contains(available, wanted []*mesos_v1.Resource) bool {
+	_, _, err := Allocate(available, wanted)
+	return err == nil
+}
+
+// Takes used resources out of the available ones, returning what's left.
+func Subtract(available, used []*mesos_v1.Resource) ([]*mesos_v1.Resource, error) {
+	_, remaining, err := Allocate(available, used)
+	return remaining, err
+}
+
+// Adds up the scalar resources with the given name.
+func Sum(res []*mesos_v1.Resource, name string) float64 {
+	var sum float64
+	for _, r := range res {
+		if r.GetName() == name && r.GetType() == mesos_v1.Value_SCALAR {
+			sum += r.GetScalar().GetValue()
+		}
+	}
+
+	return sum
+}
+
+// Tells us if an available resource can be used for a wanted one.
+func matches(available, wanted *mesos_v1.Resource) bool {
+	if available.GetName() != wanted.GetName() || available.GetType() != wanted.GetType() {
+		return false
+	}
+
+	if wanted.Role != nil && wanted.GetRole() != "*" && available.GetRole() != wanted.GetRole() {
+		return false
+	}
+
+	if wanted.AllocationInfo != nil && AllocationRole(available) != AllocationRole(wanted) {
+		return false
+	}
+
+	if id := wanted.GetDisk().GetPersistence().GetId(); id != "" {
+		return available.GetDisk().GetPersistence().GetId() == id
+	}
+
+	// Don't hand out somebody's persistent volume as plain disk.
+	return available.GetDisk().GetPersistence() == nil
+}
+
+// Takes a single wanted resource out of the remaining ones.
+func take(remaining []*mesos_v1.Resource, wanted *mesos_v1.Resource) ([]*mesos_v1.Resource, error) {
+	switch wanted.GetType() {
+	case mesos_v1.Value_SCALAR:
+		return takeScalar(remaining, wanted)
+	case mesos_v1.Value_RANGES:
+		return takeRanges(remaining, wanted)
+	case mesos_v1.Value_SET:
+		return takeSet(remaining, wanted)
+	}
+
+	return nil, errors.New("Unsupported type " + wanted.GetType().String() + " for resource " + wanted.GetName())
+}
+
+// Splits wanted ranges into the ones asked for explicitly and a single range asking for the rest by count.
+// Either of them is nil if nothing was asked for that way.
+func splitRanges(wanted *mesos_v1.Resource) (explicit, count *mesos_v1.Resource) {
+	var ranges []*mesos_v1.Value_Range
+	var n uint64
+	for _, r := range wanted.GetRanges().GetRange() {
+		if r.GetBegin() == 0 {
+			n += r.GetEnd() + 1
+			continue
+		}
+		ranges = append(ranges, r)
+	}
+
+	if len(ranges) > 0 {
+		explicit = proto.Clone(wanted).(*mesos_v1.Resource)
+		explicit.Ranges = &mesos_v1.Value_Ranges{Range: ranges}
+	}
+	if n > 0 {
+		count = proto.Clone(wanted).(*mesos_v1.Resource)
+		count.Ranges = &mesos_v1.Value_Ranges{Range: []*mesos_v1.Value_Range{CreateRange(0, n-1)}}
+	}
+
+	return explicit, count
+}
+
+func takeScalar(remaining []*mesos_v1.Resource, wanted *mesos_v1.Resource) ([]*mesos_v1.Resource, error) {
+	need := wanted.GetScalar().GetValue()
+
+	var total float64
+	for _, r := range remaining {
+		if matches(r, wanted) {
+			total += r.GetScalar().GetValue()
+		}
+	}
+	if total+scalarEpsilon < need {
+		return nil, errors.New("Not enough " + wanted.GetName() + ", wanted " + formatScalar(need) + " but only " +
+			formatScalar(total) + " is available")
+	}
+
+	var taken []*mesos_v1.Resource
+	for _, r := range remaining {
+		if need <= scalarEpsilon {
+			break
+		}
+		if !matches(r, wanted) || r.GetScalar().GetValue() <= scalarEpsilon {
+			continue
+		}
+
+		amount := math.Min(r.GetScalar().GetValue(), need)
+		r.Scalar.Value = utils.ProtoFloat64(roundScalar(r.GetScalar().GetValue() - amount))
+		need = roundScalar(need - amount)
+
+		t := proto.Clone(r).(*mesos_v1.Resource)
+		t.Scalar.Value = utils.ProtoFloat64(roundScalar(amount))
+		taken = append(taken, t)
+	}
+
+	return taken, nil
+}
+
+func takeRanges(remaining []*mesos_v1.Resource, wanted *mesos_v1.Resource) ([]*mesos_v1.Resource, error) {
+	taken := make(map[*mesos_v1.Resource][]*mesos_v1.Value_Range)
+	var order []*mesos_v1.Resource
+	take := func(r *mesos_v1.Resource, begin, end uint64) {
+		if _, ok := taken[r]; !ok {
+			order = append(order, r)
+		}
+		taken[r] = append(taken[r], CreateRange(begin, end))
+		removeRange(r, begin, end)
+	}
+
+	var count uint64
+	for _, w := range wanted.GetRanges().GetRange() {
+		if w.GetBegin() == 0 {
+			count += w.GetEnd() - w.GetBegin() + 1
+			continue
+		}
+
+		found := false
+		for _, r := range remaining {
+			if matches(r, wanted) && containsRange(r, w.GetBegin(), w.GetEnd()) {
+				take(r, w.GetBegin(), w.GetEnd())
+				found = true
+				break
+			}
+		}
+
+		if !found {
+			return nil, errors.New(wanted.GetName() + " " + strconv.FormatUint(w.GetBegin(), 10) + "-" +
+				strconv.FormatUint(w.GetEnd(), 10) + " is not available")
+		}
+	}
+
+	for _, r := range remaining {
+		if count == 0 {
+			break
+		}
+		if !matches(r, wanted) {
+			continue
+		}
+
+		for len(r.GetRanges().GetRange()) > 0 && count > 0 {
+			first := r.Ranges.Range[0]
+			end := first.GetEnd()
+			if size := end - first.GetBegin() + 1; size > count {
+				end = first.GetBegin() + count - 1
+			}
+
+			count -= end - first.GetBegin() + 1
+			take(r, first.GetBegin(), end)
+		}
+	}
+
+	if count > 0 {
+		return nil, errors.New("Not enough " + wanted.GetName() + " available")
+	}
+
+	allocated := make([]*mesos_v1.Resource, 0, len(order))
+	for _, r := range order {
+		t := proto.Clone(r).(*mesos_v1.Resource)
+		t.Ranges = &mesos_v1.Value_Ranges{Range: taken[r]}
+		allocated = append(allocated, t)
+	}
+
+	return allocated, nil
+}
+
+func takeSet(remaining []*mesos_v1.Resource, wanted *mesos_v1.Resource) ([]*mesos_v1.Resource, error) {
+	taken := make(map[*mesos_v1.Resource][]string)
+	var order []*mesos_v1.Resource
+
+	for _, item := range wanted.GetSet().GetItem() {
+		found := false
+		for _, r := range remaining {
+			if !matches(r, wanted) {
+				continue
+			}
+
+			for i, available := range r.GetSet().GetItem() {
+				if available != item {
+					continue
+				}
+
+				if _, ok := taken[r]; !ok {
+					order = append(order, r)
+				}
+				taken[r] = append(taken[r], item)
+				r.Set.Item = append(r.Set.Item[:i:i], r.Set.Item[i+1:]...)
+				found = true
+				break
+			}
+
+			if found {
+				break
+			}
+		}
+
+		if !found {
+			return nil, errors.New(wanted.GetName() + " " + item + " is not available")
+		}
+	}
+
+	allocated := make([]*mesos_v1.Resource, 0, len(order))
+	for _, r := range order {
+		t := proto.Clone(r).(*mesos_v1.Resource)
+		t.Set = &mesos_v1.Value_Set{Item: taken[r]}
+		allocated = append(allocated, t)
+	}
+
+	return allocated, nil
+}
+
+func containsRange(r *mesos_v1.Resource, begin, end uint64) bool {
+	for _, a := range r.GetRanges().GetRange() {
+		if a.GetBegin() <= begin && end <= a.GetEnd() {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Cuts begin through end out of the resource's ranges, splitting them where needed.
+func removeRange(r *mesos_v1.Resource, begin, end uint64) {
+	var ranges []*mesos_v1.Value_Range
+	for _, a := range r.GetRanges().GetRange() {
+		if end < a.GetBegin() || begin > a.GetEnd() {
+			ranges = append(ranges, a)
+			continue
+		}
+
+		if a.GetBegin() < begin {
+			ranges = append(ranges, CreateRange(a.GetBegin(), begin-1))
+		}
+		if end < a.GetEnd() {
+			ranges = append(ranges, CreateRange(end+1, a.GetEnd()))
+		}
+	}
+
+	r.Ranges.Range = ranges
+}
+
+// Drops resources that have nothing left in them.
+func prune(res []*mesos_v1.Resource) []*mesos_v1.Resource {
+	pruned := res[:0]
+	for _, r := range res {
+		switch r.GetType() {
+		case mesos_v1.Value_SCALAR:
+			if r.GetScalar().GetValue() <= scalarEpsilon {
+				continue
+			}
+		case mesos_v1.Value_RANGES:
+			if len(r.GetRanges().GetRange()) == 0 {
+				continue
+			}
+		case mesos_v1.Value_SET:
+			if len(r.GetSet().GetItem()) == 0 {
+				continue
+			}
+		}
+		pruned = append(pruned, r)
+	}
+
+	return pruned
+}
+
+func roundScalar(value float64) float64 {
+	return math.Floor(value*1000+0.5) / 1000
+}
+
+func formatScalar(value float64) string {
+	return strconv.FormatFloat(value, 'f', -1, 64)
+}
@@ -0,0 +1,272 @@
+// Copyright 2017 Verizon
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package resources
+
+import (
+	"github.com/golang/protobuf/proto"
+	"github.com/verizonlabs/mesos-framework-sdk/include/mesos_v1"
+	"testing"
+)
+
+// Ports in the offer, given as begin and end pairs.
+func offeredPorts(bounds ...uint64) *mesos_v1.Resource {
+	var ranges []*mesos_v1.Value_Range
+	for i := 0; i+1 < len(bounds); i += 2 {
+		ranges = append(ranges, CreateRange(bounds[i], bounds[i+1]))
+	}
+
+	return CreateRangesResource("ports", "*", ranges...)
+}
+
+// Flattens ranges into begin and end pairs so they're easy to compare.
+func bounds(res ...*mesos_v1.Resource) []uint64 {
+	var b []uint64
+	for _, r := range res {
+		for _, rng := range r.GetRanges().GetRange() {
+			b = append(b, rng.GetBegin(), rng.GetEnd())
+		}
+	}
+
+	return b
+}
+
+func equal(a, b []uint64) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+
+	return true
+}
+
+// Makes sure scalars are taken across several resources and what's left is handed back.
+func TestAllocate_Scalars(t *testing.T) {
+	t.Parallel()
+
+	available := []*mesos_v1.Resource{
+		CreateResource("cpus", "web", 1),
+		CreateResource("cpus", "*", 2),
+		CreateResource("mem", "*", 256),
+	}
+
+	allocated, remaining, err := Allocate(available, []*mesos_v1.Resource{
+		CreateResource("cpus", "", 1.5),
+		CreateResource("mem", "", 256),
+	})
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	if Sum(allocated, "cpus") != 1.5 || Sum(allocated, "mem") != 256 {
+		t.Fatal("Exactly what was wanted should have been allocated")
+	}
+	if len(allocated) != 3 || allocated[0].GetRole() != "web" || allocated[1].GetRole() != "*" {
+		t.Fatal("Allocated resources should keep the role of the resources they came from")
+	}
+	if Sum(remaining, "cpus") != 1.5 || Sum(remaining, "mem") != 0 || len(remaining) != 1 {
+		t.Fatal("What's left should be handed back with used up resources dropped")
+	}
+	if Sum(available, "cpus") != 3 || Sum(available, "mem") != 256 {
+		t.Fatal("The available resources should not be changed")
+	}
+}
+
+// Checks that resources for a role only come out of that role and that failures leave everything alone.
+func TestAllocate_Roles(t *testing.T) {
+	t.Parallel()
+
+	available := []*mesos_v1.Resource{
+		CreateResource("cpus", "*", 2),
+		CreateResource("cpus", "db", 1),
+	}
+
+	if _, _, err := Allocate(available, []*mesos_v1.Resource{CreateResource("cpus", "db", 2)}); err == nil {
+		t.Fatal("Only the db role's cpus should be used for it")
+	}
+
+	allocated, remaining, err := Allocate(available, []*mesos_v1.Resource{CreateResource("cpus", "db", 1)})
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	if len(allocated) != 1 || allocated[0].GetRole() != "db" || len(remaining) != 1 || remaining[0].GetRole() != "*" {
+		t.Fatal("The db role's cpus should have been used")
+	}
+
+	if _, _, err := Allocate(available, []*mesos_v1.Resource{{Name: proto.String("cpus"), Type: mesos_v1.Value_TEXT.Enum()}}); err == nil {
+		t.Fatal("Resources of an unsupported type should be rejected")
+	}
+}
+
+// Makes sure the disk info of what was offered is kept, such as the source of a mount disk.
+func TestTakeScalar_Disk(t *testing.T) {
+	t.Parallel()
+
+	mount := CreateResource("disk", "*", 1024)
+	mount.Disk = &mesos_v1.Resource_DiskInfo{
+		Source: &mesos_v1.Resource_DiskInfo_Source{
+			Type:  mesos_v1.Resource_DiskInfo_Source_MOUNT.Enum(),
+			Mount: &mesos_v1.Resource_DiskInfo_Source_Mount{Root: proto.String("/mnt/disk")},
+		},
+	}
+	remaining := []*mesos_v1.Resource{mount}
+
+	wanted := CreateResource("disk", "", 512)
+	wanted.Disk = &mesos_v1.Resource_DiskInfo{}
+	taken, err := takeScalar(remaining, wanted)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	if len(taken) != 1 || taken[0].GetDisk().GetSource().GetMount().GetRoot() != "/mnt/disk" {
+		t.Fatal("The offer's disk info should have been kept")
+	}
+	if remaining[0].GetScalar().GetValue() != 512 {
+		t.Fatal("What was taken should have been subtracted")
+	}
+
+	if _, err := takeScalar(remaining, CreateResource("disk", "", 1024)); err == nil {
+		t.Fatal("Taking more than is left should fail")
+	}
+}
+
+// Checks that persistent volumes are only handed out for the same persistence ID.
+func TestTakeScalar_Persistence(t *testing.T) {
+	t.Parallel()
+
+	volume := func(id string) *mesos_v1.Resource {
+		r := CreateResource("disk", "db", 100)
+		r.Disk = &mesos_v1.Resource_DiskInfo{
+			Persistence: &mesos_v1.Resource_DiskInfo_Persistence{Id: proto.String(id)},
+		}
+		return r
+	}
+	remaining := []*mesos_v1.Resource{volume("other"), volume("data")}
+
+	taken, err := takeScalar(remaining, volume("data"))
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	if len(taken) != 1 || taken[0].GetDisk().GetPersistence().GetId() != "data" {
+		t.Fatal("The volume with the same persistence ID should have been taken")
+	}
+
+	if _, err := takeScalar(remaining, CreateResource("disk", "db", 50)); err == nil {
+		t.Fatal("Persistent volumes should not be handed out as plain disk")
+	}
+}
+
+// Makes sure explicit ranges come out of the ranges that contain them, splitting them up.
+func TestTakeRanges(t *testing.T) {
+	t.Parallel()
+
+	remaining := []*mesos_v1.Resource{offeredPorts(31000, 31010)}
+	taken, err := takeRanges(remaining, CreatePortsResource("", 31005))
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	if !equal(bounds(taken...), []uint64{31005, 31005}) {
+		t.Fatal("The port asked for should have been taken")
+	}
+	if !equal(bounds(remaining...), []uint64{31000, 31004, 31006, 31010}) {
+		t.Fatal("The port should have been cut out of the offered range")
+	}
+
+	if _, err := takeRanges(remaining, CreatePortsResource("", 31005)); err == nil {
+		t.Fatal("A port that was already taken should not be available")
+	}
+}
+
+// Checks that ranges asked for by count are handed out lowest first, across ranges if needed.
+func TestTakeRanges_Count(t *testing.T) {
+	t.Parallel()
+
+	remaining := []*mesos_v1.Resource{offeredPorts(31000, 31001, 32000, 32005)}
+	taken, err := takeRanges(remaining, CreatePortsResource("", 0, 0, 0))
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	if !equal(bounds(taken...), []uint64{31000, 31001, 32000, 32000}) {
+		t.Fatal("The lowest free ports should have been taken")
+	}
+	if !equal(bounds(remaining...), []uint64{32001, 32005}) {
+		t.Fatal("The ports taken should no longer be available")
+	}
+
+	if _, err := takeRanges(remaining, CreatePortsResource("", 0, 0, 0, 0, 0, 0)); err == nil {
+		t.Fatal("Asking for more ports than are left should fail")
+	}
+}
+
+// Makes sure set items are taken one by one.
+func TestTakeSet(t *testing.T) {
+	t.Parallel()
+
+	remaining := []*mesos_v1.Resource{CreateSetResource("devices", "*", "gpu0", "gpu1", "gpu2")}
+	taken, err := takeSet(remaining, CreateSetResource("devices", "", "gpu2", "gpu0"))
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	items := taken[0].GetSet().GetItem()
+	if len(taken) != 1 || len(items) != 2 || items[0] != "gpu2" || items[1] != "gpu0" {
+		t.Fatal("The items asked for should have been taken")
+	}
+	if left := remaining[0].GetSet().GetItem(); len(left) != 1 || left[0] != "gpu1" {
+		t.Fatal("Only the item nobody asked for should be left")
+	}
+
+	if _, err := takeSet(remaining, CreateSetResource("devices", "", "gpu0")); err == nil {
+		t.Fatal("Items that were already taken should not be available")
+	}
+}
+
+// Checks that ports asked for by count never take a port someone else asked for by number.
+func TestAllocateAll_Ports(t *testing.T) {
+	t.Parallel()
+
+	available := []*mesos_v1.Resource{offeredPorts(31000, 31002)}
+	allocated, remaining, err := AllocateAll(available, [][]*mesos_v1.Resource{
+		{CreatePortsResource("", 0)},
+		{CreatePortsResource("", 31000)},
+	})
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	if !equal(bounds(allocated[0]...), []uint64{31001, 31001}) || !equal(bounds(allocated[1]...), []uint64{31000, 31000}) {
+		t.Fatal("The explicit port should have been taken before the one asked for by count")
+	}
+	if !equal(bounds(remaining...), []uint64{31002, 31002}) {
+		t.Fatal("Only the unused port should be left")
+	}
+}
+
+// Makes sure ports of 0 are merged into a single range asking for that many ports.
+func TestCreatePortsResource(t *testing.T) {
+	t.Parallel()
+
+	if !equal(bounds(CreatePortsResource("", 0, 8080, 0, 0)), []uint64{8080, 8080, 0, 2}) {
+		t.Fatal("Ports of 0 should be merged into one range")
+	}
+	if !equal(bounds(CreatePortsResource("", 0)), []uint64{0, 0}) {
+		t.Fatal("A single port of 0 should ask for one port")
+	}
+}
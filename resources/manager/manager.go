@@ -15,16 +15,29 @@
 package manager
 
 import (
-	"errors"
+	"context"
 	"github.com/verizonlabs/mesos-framework-sdk/include/mesos_v1"
+	"github.com/verizonlabs/mesos-framework-sdk/logging"
+	"github.com/verizonlabs/mesos-framework-sdk/resources"
 	"github.com/verizonlabs/mesos-framework-sdk/task"
 	"github.com/verizonlabs/mesos-framework-sdk/task/manager"
+	"net/http"
 	"strconv"
 	"strings"
+	"sync"
+	"time"
 )
 
 /*
 The resource manager will handle offers and allocate it to a task.
+
+Offers are held across OFFERS events until they're used, rescinded or held for longer than the configured limit.
+Expired offers, and offers that would put us over our hoarding limits, are declined on our behalf
+so that other frameworks get a chance at them.
+Offers handed out by Offers and OffersByRole are still held by us and can still be assigned to tasks,
+so they must only ever be declined through DeclineUnused.
+Offers with tasks assigned are assumed to be accepted by the next OFFERS event, report failed accept calls through
+AcceptFailed so the tasks get another chance. Without a decliner nothing is held, every batch replaces the last one.
 */
 
 type (
@@ -32,21 +45,65 @@ type (
 		AddOffers(offers []*mesos_v1.Offer)
 		HasResources() bool
 		Assign(task *manager.Task) (*mesos_v1.Offer, error)
+		AssignAll(tasks []*manager.Task) ([]*mesos_v1.Offer, error)
 		Offers() []*mesos_v1.Offer
+		OffersByRole() map[string][]*mesos_v1.Offer
+		Rescind(offerId *mesos_v1.OfferID) []*manager.Task
+		AcceptFailed(offerId *mesos_v1.OfferID) []*manager.Task
+		DeclineUnused()
+		TrackTask(task *manager.Task)
+		ForgetTask(task *manager.Task)
+	}
+
+	// Declines offers on behalf of the resource manager, the scheduler satisfies this.
+	Decliner interface {
+		Decline(offerIds []*mesos_v1.OfferID, filters *mesos_v1.Filters) (*http.Response, error)
+	}
+
+	// Unused offers are declined after 30 seconds and refused for 5 seconds, Run checks for them every second.
+	// Nothing caps how much we hold unless limits are given.
+	ResourceManagerConfig struct {
+		MaxHoldTime    time.Duration // How long an unused offer is held before it's declined.
+		RefuseSeconds  float64       // How long Mesos holds back resources we decline.
+		ExpireInterval time.Duration // How often Run looks for offers that were held too long.
+
+		// Limits on how much of the cluster we hold at once, zero means no limit.
+		MaxOffers int
+		MaxCpus   float64
+		MaxMem    float64
+
+		// Tells us which agents are going down for maintenance so that tasks aren't placed on them.
+		Draining func(agentId *mesos_v1.AgentID) bool
 	}
 
 	// A resource manager implementation.
 	DefaultResourceManager struct {
-		offers []*MesosOfferResources
+		offers   []*MesosOfferResources
+		byId     map[string][]*MesosOfferResources // Offers we still hold, one per role they were allocated to.
+		assigned map[string][]*manager.Task        // Tasks tentatively assigned to each offer until it's accepted.
+
+		strategies map[string]PlacementStrategy // Keyed by the strategy type tasks ask for.
+		placements *Placements
+		placed     map[string]string // Agent each task we know of was placed on, keyed by task ID.
+
+		decliner Decliner
+		config   ResourceManagerConfig
+		logger   logging.Logger
+		lock     sync.Mutex
 	}
 
 	// Holds offer data
 	MesosOfferResources struct {
 		Offer    *mesos_v1.Offer
+		Role     string // Role the resources were allocated to, empty if the framework isn't multi-role.
 		Cpu      float64
 		Mem      float64
 		Disk     *mesos_v1.Resource_DiskInfo
 		Accepted bool
+		Received time.Time
+
+		// Resources still left for the role, Cpu and Mem are kept as totals of these.
+		Resources []*mesos_v1.Resource
 	}
 )
 
@@ -58,46 +115,282 @@ const (
 )
 
 // Creates a default resource manager implementation.
+// Without anything to decline offers with, offers are only kept until the next batch comes in.
 func NewDefaultResourceManager() *DefaultResourceManager {
+	return NewDefaultResourceManagerWithConfig(nil, ResourceManagerConfig{}, logging.NewDefaultLogger())
+}
+
+// Creates a resource manager that declines offers it doesn't need through the given decliner.
+func NewDefaultResourceManagerWithConfig(decliner Decliner, config ResourceManagerConfig, logger logging.Logger) *DefaultResourceManager {
+	if config.MaxHoldTime <= 0 {
+		config.MaxHoldTime = 30 * time.Second
+	}
+	if config.RefuseSeconds <= 0 {
+		config.RefuseSeconds = 5
+	}
+	if config.ExpireInterval <= 0 {
+		config.ExpireInterval = time.Second
+	}
+
 	return &DefaultResourceManager{
-		offers: make([]*MesosOfferResources, 0),
+		offers:     make([]*MesosOfferResources, 0),
+		byId:       make(map[string][]*MesosOfferResources),
+		assigned:   make(map[string][]*manager.Task),
+		strategies: defaultStrategies(),
+		placements: NewPlacements(),
+		placed:     make(map[string]string),
+		decliner:   decliner,
+		config:     config,
+		logger:     logger,
 	}
 }
 
 // Add in a new batch of offers
+// Offers that were used since the last batch are dropped since they've been accepted by now,
+// while unused ones are held on to until they expire.
+// Without a decliner we can't give offers back, so everything from the last batch is dropped instead.
 func (d *DefaultResourceManager) AddOffers(offers []*mesos_v1.Offer) {
-	// No matter what, we clear offers on this call to make sure
-	// we don't have stale offers that are already declined.
-	d.clearOffers()
-	// Organize each offer into a MesosOfferResource struct.
+	d.lock.Lock()
+	var decline []*mesos_v1.OfferID
+	if d.decliner == nil {
+		d.clearOffers()
+	} else {
+		d.clearUsed()
+		decline = d.expire()
+	}
+
+	// Organize each offer into a MesosOfferResource struct per role it was allocated to.
+	now := time.Now()
 	for _, offer := range offers {
-		mesosOffer := &MesosOfferResources{}
+		if d.hoarding(offer) {
+			decline = append(decline, offer.GetId())
+			continue
+		}
+		d.placements.observe(offer)
+
+		byRole := make(map[string]*MesosOfferResources)
+		var roles []string
 		for _, resource := range offer.Resources {
+			role := resources.AllocationRole(resource)
+			mesosOffer, ok := byRole[role]
+			if !ok {
+				mesosOffer = &MesosOfferResources{Offer: offer, Role: role, Received: now}
+				byRole[role] = mesosOffer
+				roles = append(roles, role)
+			}
+			mesosOffer.Resources = append(mesosOffer.Resources, resource)
+
 			switch resource.GetName() {
 			case "cpus":
-				mesosOffer.Cpu = resource.GetScalar().GetValue()
+				mesosOffer.Cpu += resource.GetScalar().GetValue()
 			case "mem":
-				mesosOffer.Mem = resource.GetScalar().GetValue()
+				mesosOffer.Mem += resource.GetScalar().GetValue()
 			case "disk":
 				mesosOffer.Disk = resource.GetDisk()
 			}
 		}
-		mesosOffer.Offer = offer
-		// Append to the slice of offers.
-		d.offers = append(d.offers, mesosOffer)
+
+		// Append to the slice of offers, keeping roles in the order they were offered.
+		for _, role := range roles {
+			d.offers = append(d.offers, byRole[role])
+			d.byId[offer.GetId().GetValue()] = append(d.byId[offer.GetId().GetValue()], byRole[role])
+		}
+	}
+	d.lock.Unlock()
+
+	d.decline(decline)
+}
+
+// Drops offers that had tasks assigned to them, along with the assignments.
+func (d *DefaultResourceManager) clearUsed() {
+	for id := range d.assigned {
+		d.remove(id)
 	}
+	d.assigned = make(map[string][]*manager.Task)
 }
 
-// Clear out existing offers if any exist.
+// Drops every offer we hold, since they're declined for us when the next batch is sent.
 func (d *DefaultResourceManager) clearOffers() {
-	d.offers = nil
+	d.offers = make([]*MesosOfferResources, 0)
+	d.byId = make(map[string][]*MesosOfferResources)
+	d.assigned = make(map[string][]*manager.Task)
+}
+
+// Removes every trace of an offer we no longer hold.
+func (d *DefaultResourceManager) remove(id string) {
+	delete(d.byId, id)
+
+	offers := d.offers[:0]
+	for _, o := range d.offers {
+		if o.Offer.GetId().GetValue() != id {
+			offers = append(offers, o)
+		}
+	}
+	d.offers = offers
+}
+
+// Drops unused offers that were held for too long, handing back the ones that should be declined.
+func (d *DefaultResourceManager) expire() []*mesos_v1.OfferID {
+	var expired []*mesos_v1.OfferID
+	for id, held := range d.byId {
+		if _, ok := d.assigned[id]; ok || time.Since(held[0].Received) < d.config.MaxHoldTime {
+			continue
+		}
+
+		expired = append(expired, held[0].Offer.GetId())
+		d.remove(id)
+	}
+
+	return expired
+}
+
+// Declines offers as they expire until the context is done.
+// New offers and plans only look for expired offers when they come in, which never happens while offers are suppressed.
+func (d *DefaultResourceManager) Run(ctx context.Context) error {
+	ticker := time.NewTicker(d.config.ExpireInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			d.declineExpired()
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
+
+// Declines whatever was held for too long.
+func (d *DefaultResourceManager) declineExpired() {
+	d.lock.Lock()
+	decline := d.expire()
+	d.lock.Unlock()
+
+	d.decline(decline)
+}
+
+// Tells us if holding on to the offer would put us over our limits.
+func (d *DefaultResourceManager) hoarding(offer *mesos_v1.Offer) bool {
+	if d.config.MaxOffers > 0 && len(d.byId) >= d.config.MaxOffers {
+		return true
+	}
+
+	var cpu, mem float64
+	for _, o := range d.offers {
+		cpu += o.Cpu
+		mem += o.Mem
+	}
+	for _, r := range offer.Resources {
+		switch r.GetName() {
+		case "cpus":
+			cpu += r.GetScalar().GetValue()
+		case "mem":
+			mem += r.GetScalar().GetValue()
+		}
+	}
+
+	return (d.config.MaxCpus > 0 && cpu > d.config.MaxCpus) || (d.config.MaxMem > 0 && mem > d.config.MaxMem)
+}
+
+// Declines offers without holding the lock, since it goes out to Mesos.
+func (d *DefaultResourceManager) decline(offerIds []*mesos_v1.OfferID) {
+	if len(offerIds) == 0 || d.decliner == nil {
+		return
+	}
+
+	refuse := d.config.RefuseSeconds
+	resp, err := d.decliner.Decline(offerIds, &mesos_v1.Filters{RefuseSeconds: &refuse})
+	if resp != nil && resp.Body != nil {
+		resp.Body.Close()
+	}
+	if err != nil {
+		d.logger.Emit(logging.ERROR, "Failed to decline %d offers: %s", len(offerIds), err.Error())
+	}
+}
+
+// Declines every offer we hold that doesn't have tasks assigned to it, such as when there's nothing left to launch.
+// This is the only safe way to decline offers we hold since it also stops them from being assigned.
+func (d *DefaultResourceManager) DeclineUnused() {
+	d.lock.Lock()
+	var unused []*mesos_v1.OfferID
+	for id, held := range d.byId {
+		if _, ok := d.assigned[id]; ok {
+			continue
+		}
+
+		unused = append(unused, held[0].Offer.GetId())
+		d.remove(id)
+	}
+	d.lock.Unlock()
+
+	d.decline(unused)
+}
+
+// Tells us if the offer's agent is being drained for maintenance.
+func (d *DefaultResourceManager) draining(offer *MesosOfferResources) bool {
+	return d.config.Draining != nil && d.config.Draining(offer.Offer.GetAgentId())
 }
 
 // Do we have any resources left?
 func (d *DefaultResourceManager) HasResources() bool {
+	d.lock.Lock()
+	defer d.lock.Unlock()
+
 	return len(d.offers) > 0
 }
 
+// Drops an offer that Mesos took back and rolls back every task that was assigned to it.
+// Rolled back tasks are put back in STAGING and returned so they can be updated in the task manager,
+// which makes them pending again for the next round of offers.
+func (d *DefaultResourceManager) Rescind(offerId *mesos_v1.OfferID) []*manager.Task {
+	d.lock.Lock()
+	defer d.lock.Unlock()
+
+	return d.rollback(offerId.GetValue())
+}
+
+// Rolls back the tasks assigned to an offer we couldn't accept, the same way Rescind does.
+// The offer can't be trusted anymore so it's dropped and declined in case the master still thinks we hold it.
+func (d *DefaultResourceManager) AcceptFailed(offerId *mesos_v1.OfferID) []*manager.Task {
+	d.lock.Lock()
+	_, held := d.byId[offerId.GetValue()]
+	tasks := d.rollback(offerId.GetValue())
+	d.lock.Unlock()
+
+	if held {
+		d.decline([]*mesos_v1.OfferID{offerId})
+	}
+
+	return tasks
+}
+
+// Drops an offer along with its assignments, handing back the tasks that were assigned to it.
+func (d *DefaultResourceManager) rollback(id string) []*manager.Task {
+	held := d.byId[id]
+	d.remove(id)
+
+	tasks := d.assigned[id]
+	delete(d.assigned, id)
+
+	for _, t := range tasks {
+		t.State = manager.STAGING
+		t.Info.Resources = requested(t)
+		d.unplace(t)
+
+		// Only clear what we set when assigning the offer.
+		for _, o := range held {
+			if t.Info.GetAgentId().GetValue() == o.Offer.GetAgentId().GetValue() {
+				t.Info.AgentId = nil
+			}
+			if o.Role != "" {
+				d.deallocate(t)
+			}
+		}
+	}
+
+	return tasks
+}
+
 // Swaps current element with last, then sets the entire slice to the slice without the last element.
 // Faster than taking two slices around the element and re-combining them since no resizing occurs
 // and we don't care about order.
@@ -159,103 +452,207 @@ func (d *DefaultResourceManager) filter(f []task.Filter, offer *mesos_v1.Offer)
 	return false
 }
 
-// allocateMemResources returns a boolean and tells us if we have enough memory resources on this offer.
-func (d *DefaultResourceManager) allocateMemResource(mem float64, offer *MesosOfferResources) bool {
-	if offer.Mem-mem >= 0 {
-		offer.Mem = offer.Mem - mem
-		return true
+// If a task has offer filters but the offer doesn't satisfy them, return false, otherwise true.
+func (d *DefaultResourceManager) filterOnOffer(task *manager.Task, offer *MesosOfferResources) bool {
+	validOffer := d.filter(task.Filters, offer.Offer)
+	if !validOffer {
+		// We don't care about this offer since it does't match our params.
+		return false
 	}
+	return true
+}
 
-	return false
+// Resources the task asked for, before any offer was assigned to it.
+func requested(task *manager.Task) []*mesos_v1.Resource {
+	if task.Requested != nil {
+		return task.Requested
+	}
+
+	return task.Info.Resources
 }
 
-// allocateCpuResources returns a boolean and tells us if we have enough cpu resources on this offer.
-func (d *DefaultResourceManager) allocateCpuResource(cpu float64, offer *MesosOfferResources) bool {
-	if offer.Cpu-cpu >= 0 {
-		offer.Cpu = offer.Cpu - cpu
-		return true
+// Assign an offer to a task.
+// This plans the one task and commits it straight away, see Plan for how offers are picked.
+func (d *DefaultResourceManager) Assign(task *manager.Task) (*mesos_v1.Offer, error) {
+	offers, err := d.AssignAll([]*manager.Task{task})
+	if err != nil {
+		return nil, err
 	}
 
-	return false
+	return offers[0], nil
 }
 
-// allocateDiskResource returns a boolean and tells us if we have enough disk resources on this offer.
-func (d *DefaultResourceManager) allocateDiskResource(resource *mesos_v1.Resource, offer *MesosOfferResources) bool {
-	if resource.Disk != nil {
-		offer.Disk = resource.Disk
-		return true
+// Assigns offers to a batch of tasks, returning the offer each task got in the same order.
+// Either every task gets an offer or none of them do, tasks can share an offer as long as it fits them all.
+func (d *DefaultResourceManager) AssignAll(tasks []*manager.Task) ([]*mesos_v1.Offer, error) {
+	p := d.Plan()
+
+	offers := make([]*mesos_v1.Offer, 0, len(tasks))
+	for _, t := range tasks {
+		offer, err := p.Assign(t)
+		if err != nil {
+			p.Rollback()
+			return nil, err
+		}
+		offers = append(offers, offer)
+	}
+
+	if err := p.Commit(); err != nil {
+		return nil, err
+	}
+
+	return offers, nil
+}
+
+// Tells us if an offer can still be assigned to tasks.
+func (d *DefaultResourceManager) holds(offer *MesosOfferResources) bool {
+	for _, o := range d.offers {
+		if o == offer {
+			return true
+		}
 	}
 
 	return false
 }
 
-// If a task has offer filters but the offer doesn't satisfy them, return false, otherwise true.
-func (d *DefaultResourceManager) filterOnOffer(task *manager.Task, offer *MesosOfferResources) bool {
-	validOffer := d.filter(task.Filters, offer.Offer)
-	if !validOffer {
-		// We don't care about this offer since it does't match our params.
-		return false
+// Marks an offer as used by a task.
+// If the task has no filters to apply or no filters match then we're done with the offer.
+// Accepting an offer uses up all of it, so what was allocated to the offer's other roles goes along with it.
+func (d *DefaultResourceManager) use(task *manager.Task, offer *MesosOfferResources) {
+	held := d.byId[offer.Offer.GetId().GetValue()]
+	if len(task.Filters) == 0 || !d.filterOnOffer(task, offer) {
+		for _, o := range held {
+			for i := range d.offers {
+				if d.offers[i] == o {
+					d.popOffer(i)
+					break
+				}
+			}
+		}
+	} else {
+		for _, o := range held {
+			o.Accepted = true
+		}
 	}
-	return true
 }
 
-// Check if an offer has enough resources for a task's request.
-func (d *DefaultResourceManager) hasSufficientResources(task *manager.Task, offer *MesosOfferResources) bool {
-	// Eat up this offer's resources with the task's needs.
-	for _, resource := range task.Info.Resources {
-		res := resource.GetScalar().GetValue()
+// Registers a placement strategy, or replaces one, for tasks that ask for it by name.
+func (d *DefaultResourceManager) RegisterStrategy(name string, s PlacementStrategy) {
+	d.lock.Lock()
+	defer d.lock.Unlock()
 
-		switch resource.GetName() {
-		case "cpus":
-			if d.allocateCpuResource(res, offer) {
-				break
-			}
+	d.strategies[name] = s
+}
 
-			// We can't use this offer if it has no CPUs, move on to the next offer.
-			return false
-		case "mem":
-			if d.allocateMemResource(res, offer) {
-				break
-			}
+// Lets placement strategies know about a task that's already running, such as after a restart.
+func (d *DefaultResourceManager) TrackTask(task *manager.Task) {
+	d.lock.Lock()
+	defer d.lock.Unlock()
 
-			// We can't use this offer if it has no memory, move on to the next offer.
-			return false
-		case "disk":
-			d.allocateDiskResource(resource, offer)
-		}
+	if _, ok := d.placed[task.Info.GetTaskId().GetValue()]; ok || task.Info.GetAgentId() == nil {
+		return
 	}
-	return true
+	d.place(task, task.Info.GetAgentId().GetValue())
 }
 
-// Assign an offer to a task.
-func (d *DefaultResourceManager) Assign(task *manager.Task) (*mesos_v1.Offer, error) {
-	for i, offer := range d.offers {
-		// First check if we even have enough resources before even looking at filters.
-		if !d.hasSufficientResources(task, offer) {
-			continue
-		}
+// Forgets where a task was placed once it's no longer running.
+// Nothing else removes a placement, so call this from your status update handling for every terminal update,
+// or strategies such as Unique will keep counting the task against its agent.
+func (d *DefaultResourceManager) ForgetTask(task *manager.Task) {
+	d.lock.Lock()
+	defer d.lock.Unlock()
 
-		// If the task has no filters to apply or no filters match then return the offer.
-		if len(task.Filters) == 0 || !d.filterOnOffer(task, offer) {
-			d.popOffer(i)
-			return offer.Offer, nil
-		}
+	d.unplace(task)
+}
+
+// Records where a task was placed, moving it if it was placed somewhere before.
+func (d *DefaultResourceManager) place(task *manager.Task, agent string) {
+	d.unplace(task)
+	d.placements.Add(task, agent)
+	d.placed[task.Info.GetTaskId().GetValue()] = agent
+}
 
-		if d.filterOnOffer(task, offer) {
-			d.offers[i].Accepted = true
-			return offer.Offer, nil
+func (d *DefaultResourceManager) unplace(task *manager.Task) {
+	id := task.Info.GetTaskId().GetValue()
+	agent, ok := d.placed[id]
+	if !ok {
+		return
+	}
+
+	d.placements.Remove(task, agent)
+	delete(d.placed, id)
+}
+
+// Marks the task's resources, along with its executor's, as allocated to the offer's role.
+func (d *DefaultResourceManager) allocate(task *manager.Task, offer *MesosOfferResources) {
+	if offer.Role == "" {
+		return
+	}
+
+	resources.AllocateResources(offer.Role, task.Info.Resources...)
+	if e := executor(task); e != nil {
+		resources.AllocateResources(offer.Role, e.Resources...)
+	}
+}
+
+// Strips the allocation role set by allocate.
+func (d *DefaultResourceManager) deallocate(task *manager.Task) {
+	for _, r := range task.Info.Resources {
+		r.AllocationInfo = nil
+	}
+	if e := executor(task); e != nil {
+		for _, r := range e.Resources {
+			r.AllocationInfo = nil
 		}
 	}
+}
 
-	return nil, errors.New("Cannot find a suitable offer for task " + task.Info.GetName())
+// Gets the executor a task runs on, which for task groups is shared by the whole group.
+func executor(task *manager.Task) *mesos_v1.ExecutorInfo {
+	if task.Info.Executor != nil {
+		return task.Info.Executor
+	}
+
+	return task.GroupInfo.Executor
+}
+
+// Remembers which offer a task was given so that it can be rolled back if the offer is rescinded.
+func (d *DefaultResourceManager) assign(task *manager.Task, offer *MesosOfferResources) {
+	id := offer.Offer.GetId().GetValue()
+	d.assigned[id] = append(d.assigned[id], task)
 }
 
 // Returns a list of offers that have not been altered and returned to the client for accept calls.
+// Offers allocated to several roles are only listed once.
+// We keep holding on to these, so use DeclineUnused instead of declining them yourself.
 func (d *DefaultResourceManager) Offers() (offers []*mesos_v1.Offer) {
+	d.lock.Lock()
+	defer d.lock.Unlock()
+
+	seen := make(map[string]bool)
 	for _, o := range d.offers {
-		if !o.Accepted {
+		id := o.Offer.GetId().GetValue()
+		if !o.Accepted && !seen[id] {
+			seen[id] = true
 			offers = append(offers, o.Offer)
 		}
 	}
 	return offers
 }
+
+// Groups the offers that have not been altered by the role they were allocated to.
+// Offers to frameworks that aren't multi-role are grouped under an empty role.
+// Just like with Offers, these are still held and only DeclineUnused should decline them.
+func (d *DefaultResourceManager) OffersByRole() map[string][]*mesos_v1.Offer {
+	d.lock.Lock()
+	defer d.lock.Unlock()
+
+	offers := make(map[string][]*mesos_v1.Offer)
+	for _, o := range d.offers {
+		if !o.Accepted {
+			offers[o.Role] = append(offers[o.Role], o.Offer)
+		}
+	}
+
+	return offers
+}
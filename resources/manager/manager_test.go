@@ -0,0 +1,586 @@
+// Copyright 2017 Verizon
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package manager
+
+import (
+	"bytes"
+	"context"
+	"github.com/golang/protobuf/proto"
+	"github.com/verizonlabs/mesos-framework-sdk/include/mesos_v1"
+	"github.com/verizonlabs/mesos-framework-sdk/resources"
+	"github.com/verizonlabs/mesos-framework-sdk/task/manager"
+	"io/ioutil"
+	"net/http"
+	"sort"
+	"sync"
+	"testing"
+	"time"
+)
+
+type mockLogger struct{}
+
+func (m *mockLogger) Emit(severity uint8, template string, args ...interface{}) {
+
+}
+
+var l = new(mockLogger)
+
+func mockOffer(id, agent string, res ...*mesos_v1.Resource) *mesos_v1.Offer {
+	return &mesos_v1.Offer{
+		Id:        &mesos_v1.OfferID{Value: proto.String(id)},
+		AgentId:   &mesos_v1.AgentID{Value: proto.String(agent)},
+		Resources: res,
+	}
+}
+
+func mockTask(id string, res ...*mesos_v1.Resource) *manager.Task {
+	return &manager.Task{
+		Info: &mesos_v1.TaskInfo{
+			Name:      proto.String(id),
+			TaskId:    &mesos_v1.TaskID{Value: proto.String(id)},
+			Resources: res,
+		},
+		State: manager.UNKNOWN,
+	}
+}
+
+// Offered cpu and memory, unreserved.
+func scalars(cpu, mem float64) []*mesos_v1.Resource {
+	return []*mesos_v1.Resource{
+		resources.CreateResource("cpus", "*", cpu),
+		resources.CreateResource("mem", "*", mem),
+	}
+}
+
+// Cpu and memory asked for by a task.
+func wants(cpu, mem float64) []*mesos_v1.Resource {
+	return []*mesos_v1.Resource{
+		resources.CreateResource("cpus", "", cpu),
+		resources.CreateResource("mem", "", mem),
+	}
+}
+
+// Makes sure tasks aren't placed on agents that are being drained for maintenance.
+func TestDefaultResourceManager_AssignDraining(t *testing.T) {
+	t.Parallel()
+
+	d := NewDefaultResourceManagerWithConfig(nil, ResourceManagerConfig{
+		Draining: func(agentId *mesos_v1.AgentID) bool {
+			return agentId.GetValue() == "maintenance"
+		},
+	}, l)
+	d.AddOffers([]*mesos_v1.Offer{
+		mockOffer("drained", "maintenance", scalars(4, 1024)...),
+		mockOffer("healthy", "other", scalars(1, 128)...),
+	})
+
+	offer, err := d.Assign(mockTask("task", wants(1, 128)...))
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	if offer.GetId().GetValue() != "healthy" {
+		t.Fatal("Task should not have been placed on an agent under maintenance")
+	}
+
+	if _, err := d.Assign(mockTask("another", wants(1, 128)...)); err == nil {
+		t.Fatal("Only the offer from the draining agent is left, which should not be used")
+	}
+}
+
+// Cpu and memory allocated to a role of a multi-role framework.
+func allocated(role string, cpu, mem float64) []*mesos_v1.Resource {
+	res := scalars(cpu, mem)
+	resources.AllocateResources(role, res...)
+
+	return res
+}
+
+// Checks that offers are grouped by the role they were allocated to and that tasks only get resources for their role.
+func TestDefaultResourceManager_Roles(t *testing.T) {
+	t.Parallel()
+
+	d := NewDefaultResourceManagerWithConfig(nil, ResourceManagerConfig{}, l)
+	d.AddOffers([]*mesos_v1.Offer{
+		mockOffer("shared", "agent", append(allocated("web", 1, 128), allocated("db", 2, 256)...)...),
+		mockOffer("db-only", "other", allocated("db", 1, 128)...),
+	})
+
+	byRole := d.OffersByRole()
+	if len(byRole) != 2 || len(byRole["web"]) != 1 || len(byRole["db"]) != 2 {
+		t.Fatal("Offers were not grouped by role")
+	}
+	if len(d.Offers()) != 2 {
+		t.Fatal("Offers allocated to several roles should only be listed once")
+	}
+
+	web := mockTask("web", wants(1, 128)...)
+	web.Role = "web"
+	if _, err := d.Assign(mockTask("too-big", wants(2, 256)...)); err != nil {
+		t.Fatal("Tasks without a role should be able to use any role's resources: " + err.Error())
+	}
+	if _, err := d.Assign(web); err == nil {
+		t.Fatal("The only offer for the web role was already used")
+	}
+}
+
+// Makes sure using one role's share of an offer uses up the rest of the offer too.
+func TestDefaultResourceManager_RolesUsedTogether(t *testing.T) {
+	t.Parallel()
+
+	d := NewDefaultResourceManagerWithConfig(nil, ResourceManagerConfig{}, l)
+	d.AddOffers([]*mesos_v1.Offer{
+		mockOffer("shared", "agent", append(allocated("web", 1, 128), allocated("db", 2, 256)...)...),
+	})
+
+	web := mockTask("web", wants(1, 128)...)
+	web.Role = "web"
+	offer, err := d.Assign(web)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	if offer.GetId().GetValue() != "shared" {
+		t.Fatal("The task should have been given the shared offer")
+	}
+
+	if len(d.Offers()) != 0 || len(d.OffersByRole()) != 0 || d.HasResources() {
+		t.Fatal("No part of an offer should be left once it's been used")
+	}
+
+	db := mockTask("db", wants(1, 128)...)
+	db.Role = "db"
+	if _, err := d.Assign(db); err == nil {
+		t.Fatal("The db role's share of a used offer should not be handed out again")
+	}
+}
+
+// Checks that launched resources are marked as allocated to the role they came from.
+func TestDefaultResourceManager_AllocationInfo(t *testing.T) {
+	t.Parallel()
+
+	d := NewDefaultResourceManagerWithConfig(nil, ResourceManagerConfig{}, l)
+	d.AddOffers([]*mesos_v1.Offer{
+		mockOffer("shared", "agent", append(allocated("web", 1, 128), allocated("db", 2, 256)...)...),
+	})
+
+	task := mockTask("db", wants(1, 128)...)
+	task.Role = "db"
+	task.Info.Executor = &mesos_v1.ExecutorInfo{Resources: wants(0.1, 32)}
+	if _, err := d.Assign(task); err != nil {
+		t.Fatal(err.Error())
+	}
+
+	for _, r := range task.Info.Resources {
+		if resources.AllocationRole(r) != "db" {
+			t.Fatal("Task resources should be allocated to the db role")
+		}
+	}
+	for _, r := range task.Info.Executor.Resources {
+		if resources.AllocationRole(r) != "db" {
+			t.Fatal("Executor resources should be allocated to the db role")
+		}
+	}
+
+	// Frameworks that aren't multi-role don't get allocation info.
+	d.AddOffers([]*mesos_v1.Offer{mockOffer("plain", "agent", scalars(1, 128)...)})
+	plain := mockTask("plain", wants(1, 128)...)
+	if _, err := d.Assign(plain); err != nil {
+		t.Fatal(err.Error())
+	}
+	for _, r := range plain.Info.Resources {
+		if r.AllocationInfo != nil {
+			t.Fatal("Resources should only be allocated to a role for multi-role frameworks")
+		}
+	}
+}
+
+// Checks that tasks assigned to a rescinded offer are rolled back to how they were before they were assigned.
+func TestDefaultResourceManager_Rescind(t *testing.T) {
+	t.Parallel()
+
+	d := NewDefaultResourceManagerWithConfig(nil, ResourceManagerConfig{}, l)
+	d.AddOffers([]*mesos_v1.Offer{mockOffer("offer", "agent", allocated("web", 2, 256)...)})
+
+	task := mockTask("task", wants(1, 128)...)
+	task.Role = "web"
+	task.Info.Executor = &mesos_v1.ExecutorInfo{Resources: wants(0.1, 32)}
+	asked := task.Info.Resources
+
+	offer, err := d.Assign(task)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	task.Info.AgentId = offer.AgentId
+	task.State = manager.STAGING
+
+	if d.placements.OnAgent(task, "agent") != 1 {
+		t.Fatal("The task should have been placed on the offer's agent")
+	}
+
+	rescinded := d.Rescind(offer.Id)
+	if len(rescinded) != 1 || rescinded[0] != task {
+		t.Fatal("The task assigned to the offer should have been rolled back")
+	}
+
+	if task.State != manager.STAGING {
+		t.Fatal("Rolled back tasks should be pending again")
+	}
+	if len(task.Info.Resources) != len(asked) || task.Info.Resources[0] != asked[0] {
+		t.Fatal("The task should be asking for what it originally requested")
+	}
+	for _, r := range append(task.Info.Resources, task.Info.Executor.Resources...) {
+		if r.AllocationInfo != nil {
+			t.Fatal("Rolled back resources should not be allocated to a role anymore")
+		}
+	}
+	if task.Info.AgentId != nil {
+		t.Fatal("The task should no longer be on the offer's agent")
+	}
+	if d.placements.OnAgent(task, "agent") != 0 {
+		t.Fatal("The task's placement should have been forgotten")
+	}
+	if d.HasResources() {
+		t.Fatal("The rescinded offer should be gone")
+	}
+
+	// Once rolled back the task can be assigned as if nothing happened.
+	d.AddOffers([]*mesos_v1.Offer{mockOffer("another", "other", allocated("web", 2, 256)...)})
+	if _, err := d.Assign(task); err != nil {
+		t.Fatal("Rolled back task could not be assigned again: " + err.Error())
+	}
+	if d.placements.OnAgent(task, "other") != 1 {
+		t.Fatal("The task should be placed on its new agent")
+	}
+}
+
+// Makes sure rescinding an offer we don't know about, or one nothing was assigned to, leaves everything alone.
+func TestDefaultResourceManager_RescindUnused(t *testing.T) {
+	t.Parallel()
+
+	d := NewDefaultResourceManagerWithConfig(nil, ResourceManagerConfig{}, l)
+	d.AddOffers([]*mesos_v1.Offer{
+		mockOffer("rescinded", "agent", scalars(1, 128)...),
+		mockOffer("kept", "agent", scalars(1, 128)...),
+	})
+
+	if len(d.Rescind(&mesos_v1.OfferID{Value: proto.String("unknown")})) != 0 {
+		t.Fatal("No tasks should be rolled back for an offer we never held")
+	}
+	if len(d.Rescind(&mesos_v1.OfferID{Value: proto.String("rescinded")})) != 0 {
+		t.Fatal("No tasks should be rolled back for an unused offer")
+	}
+
+	offers := d.Offers()
+	if len(offers) != 1 || offers[0].GetId().GetValue() != "kept" {
+		t.Fatal("Only the rescinded offer should have been dropped")
+	}
+}
+
+// Keeps track of every decline call.
+type recordingDecliner struct {
+	declined [][]string
+	refused  []float64
+	lock     sync.Mutex
+}
+
+func (r *recordingDecliner) Decline(offerIds []*mesos_v1.OfferID, filters *mesos_v1.Filters) (*http.Response, error) {
+	var ids []string
+	for _, id := range offerIds {
+		ids = append(ids, id.GetValue())
+	}
+	sort.Strings(ids)
+
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	r.declined = append(r.declined, ids)
+	r.refused = append(r.refused, filters.GetRefuseSeconds())
+
+	return &http.Response{StatusCode: http.StatusAccepted, Body: ioutil.NopCloser(new(bytes.Buffer))}, nil
+}
+
+// Makes sure offers held for too long are declined and can't be assigned anymore.
+func TestDefaultResourceManager_Expire(t *testing.T) {
+	t.Parallel()
+
+	r := new(recordingDecliner)
+	d := NewDefaultResourceManagerWithConfig(r, ResourceManagerConfig{MaxHoldTime: time.Minute, RefuseSeconds: 10}, l)
+	d.AddOffers([]*mesos_v1.Offer{
+		mockOffer("old", "agent", scalars(4, 1024)...),
+		mockOffer("new", "other", scalars(1, 128)...),
+	})
+	d.byId["old"][0].Received = time.Now().Add(-2 * time.Minute)
+
+	if _, err := d.Assign(mockTask("big", wants(2, 256)...)); err == nil {
+		t.Fatal("Expired offers should not be assigned")
+	}
+
+	if len(r.declined) != 1 || len(r.declined[0]) != 1 || r.declined[0][0] != "old" || r.refused[0] != 10 {
+		t.Fatal("The expired offer should have been declined with our refuse filter")
+	}
+
+	offers := d.Offers()
+	if len(offers) != 1 || offers[0].GetId().GetValue() != "new" {
+		t.Fatal("Only the offer that hasn't expired should be left")
+	}
+}
+
+// Makes sure Run declines expired offers even when no new offers come in.
+func TestDefaultResourceManager_Run(t *testing.T) {
+	t.Parallel()
+
+	r := new(recordingDecliner)
+	d := NewDefaultResourceManagerWithConfig(r, ResourceManagerConfig{
+		MaxHoldTime:    10 * time.Millisecond,
+		ExpireInterval: time.Millisecond,
+	}, l)
+	d.AddOffers([]*mesos_v1.Offer{mockOffer("held", "agent", scalars(1, 128)...)})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error)
+	go func() {
+		done <- d.Run(ctx)
+	}()
+
+	declined := func() [][]string {
+		r.lock.Lock()
+		defer r.lock.Unlock()
+
+		return r.declined
+	}
+	for deadline := time.Now().Add(5 * time.Second); len(declined()) == 0; time.Sleep(time.Millisecond) {
+		if time.Now().After(deadline) {
+			t.Fatal("The held offer was never declined")
+		}
+	}
+
+	cancel()
+	if <-done != context.Canceled {
+		t.Fatal("Run should have stopped once cancelled")
+	}
+
+	if calls := declined(); len(calls) != 1 || calls[0][0] != "held" || d.HasResources() {
+		t.Fatal("The expired offer should have been declined and dropped")
+	}
+}
+
+// Checks that offers over our limits are declined straight away.
+func TestDefaultResourceManager_Hoarding(t *testing.T) {
+	t.Parallel()
+
+	r := new(recordingDecliner)
+	d := NewDefaultResourceManagerWithConfig(r, ResourceManagerConfig{MaxOffers: 2}, l)
+	d.AddOffers([]*mesos_v1.Offer{
+		mockOffer("first", "agent", scalars(1, 128)...),
+		mockOffer("second", "agent", scalars(1, 128)...),
+		mockOffer("third", "agent", scalars(1, 128)...),
+	})
+	if len(r.declined) != 1 || len(r.declined[0]) != 1 || r.declined[0][0] != "third" || len(d.Offers()) != 2 {
+		t.Fatal("Offers over our limit on offers should have been declined")
+	}
+
+	r = new(recordingDecliner)
+	d = NewDefaultResourceManagerWithConfig(r, ResourceManagerConfig{MaxCpus: 3, MaxMem: 1024}, l)
+	d.AddOffers([]*mesos_v1.Offer{
+		mockOffer("cpu", "agent", scalars(2, 128)...),
+		mockOffer("too-much-cpu", "agent", scalars(2, 128)...),
+		mockOffer("too-much-mem", "agent", scalars(1, 1024)...),
+		mockOffer("fits", "agent", scalars(1, 128)...),
+	})
+	if len(r.declined) != 1 || len(r.declined[0]) != 2 || r.declined[0][0] != "too-much-cpu" || r.declined[0][1] != "too-much-mem" {
+		t.Fatal("Offers over our cpu or memory limits should have been declined")
+	}
+	if len(d.Offers()) != 2 {
+		t.Fatal("Offers within our limits should be held")
+	}
+}
+
+// Makes sure only offers without tasks assigned are declined, and that they're gone afterwards.
+func TestDefaultResourceManager_DeclineUnused(t *testing.T) {
+	t.Parallel()
+
+	r := new(recordingDecliner)
+	d := NewDefaultResourceManagerWithConfig(r, ResourceManagerConfig{}, l)
+	d.AddOffers([]*mesos_v1.Offer{
+		mockOffer("used", "agent", scalars(1, 128)...),
+		mockOffer("unused", "agent", scalars(1, 128)...),
+		mockOffer("also-unused", "agent", scalars(1, 128)...),
+	})
+
+	if _, err := d.Assign(mockTask("task", wants(1, 128)...)); err != nil {
+		t.Fatal(err.Error())
+	}
+
+	d.DeclineUnused()
+	if len(r.declined) != 1 || len(r.declined[0]) != 2 {
+		t.Fatal("Both unused offers should have been declined at once")
+	}
+	for _, id := range r.declined[0] {
+		if id == "used" {
+			t.Fatal("The offer with a task assigned should not be declined")
+		}
+	}
+
+	if len(d.Offers()) != 0 || d.HasResources() {
+		t.Fatal("Declined offers should no longer be held")
+	}
+	if _, err := d.Assign(mockTask("another", wants(1, 128)...)); err == nil {
+		t.Fatal("Declined offers should not be assigned")
+	}
+
+	d.DeclineUnused()
+	if len(r.declined) != 1 {
+		t.Fatal("Nothing should be declined when there's nothing left")
+	}
+}
+
+// Checks that without a decliner each batch of offers replaces the last one, since nothing we hold could be given back.
+func TestDefaultResourceManager_AddOffersWithoutDecliner(t *testing.T) {
+	t.Parallel()
+
+	d := NewDefaultResourceManager()
+	d.AddOffers([]*mesos_v1.Offer{
+		mockOffer("first", "agent", scalars(1, 128)...),
+		mockOffer("second", "agent", scalars(1, 128)...),
+	})
+	d.AddOffers([]*mesos_v1.Offer{mockOffer("third", "agent", scalars(1, 128)...)})
+
+	offers := d.Offers()
+	if len(offers) != 1 || offers[0].GetId().GetValue() != "third" {
+		t.Fatal("Only the latest batch of offers should be held")
+	}
+}
+
+// Makes sure tasks on an offer we failed to accept are rolled back and the offer is given back.
+func TestDefaultResourceManager_AcceptFailed(t *testing.T) {
+	t.Parallel()
+
+	r := new(recordingDecliner)
+	d := NewDefaultResourceManagerWithConfig(r, ResourceManagerConfig{}, l)
+	d.AddOffers([]*mesos_v1.Offer{mockOffer("failed", "agent", scalars(1, 128)...)})
+
+	task := mockTask("task", wants(1, 128)...)
+	offer, err := d.Assign(task)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	task.Info.AgentId = offer.AgentId
+
+	failed := d.AcceptFailed(offer.Id)
+	if len(failed) != 1 || failed[0] != task || task.State != manager.STAGING || task.Info.AgentId != nil {
+		t.Fatal("The task assigned to the offer should have been rolled back")
+	}
+	if len(r.declined) != 1 || r.declined[0][0] != "failed" || d.HasResources() {
+		t.Fatal("The offer we failed to accept should have been dropped and declined")
+	}
+
+	if len(d.AcceptFailed(offer.Id)) != 0 || len(r.declined) != 1 {
+		t.Fatal("An offer we no longer hold should be left alone")
+	}
+
+	d.AddOffers([]*mesos_v1.Offer{mockOffer("another", "other", scalars(1, 128)...)})
+	if _, err := d.Assign(task); err != nil {
+		t.Fatal("Rolled back task could not be assigned again: " + err.Error())
+	}
+}
+
+// Makes sure a task asking for any port doesn't take the port a later task in the same batch asked for.
+func TestDefaultResourceManager_AssignAllPorts(t *testing.T) {
+	t.Parallel()
+
+	d := NewDefaultResourceManagerWithConfig(nil, ResourceManagerConfig{}, l)
+	d.AddOffers([]*mesos_v1.Offer{
+		mockOffer("offer", "agent", append(scalars(2, 256), resources.CreateRangesResource("ports", "*",
+			resources.CreateRange(31000, 31001)))...),
+	})
+
+	anyPort := mockTask("any", append(wants(1, 128), resources.CreatePortsResource("", 0))...)
+	fixed := mockTask("fixed", append(wants(1, 128), resources.CreatePortsResource("", 31000))...)
+	if _, err := d.AssignAll([]*manager.Task{anyPort, fixed}); err != nil {
+		t.Fatal(err.Error())
+	}
+
+	port := func(task *manager.Task) uint64 {
+		for _, r := range task.Info.Resources {
+			if r.GetName() == "ports" {
+				return r.GetRanges().GetRange()[0].GetBegin()
+			}
+		}
+		return 0
+	}
+	if port(fixed) != 31000 || port(anyPort) != 31001 {
+		t.Fatal("The explicit port should have gone to the task that asked for it")
+	}
+}
+
+// Checks that a pod's executor is paid for once out of the offer its tasks are launched on.
+func TestDefaultResourceManager_AssignAllExecutor(t *testing.T) {
+	t.Parallel()
+
+	d := NewDefaultResourceManagerWithConfig(nil, ResourceManagerConfig{}, l)
+	d.AddOffers([]*mesos_v1.Offer{mockOffer("offer", "agent", scalars(2.5, 512)...)})
+
+	executor := &mesos_v1.ExecutorInfo{
+		ExecutorId: &mesos_v1.ExecutorID{Value: proto.String("pod")},
+		Resources:  wants(0.5, 256),
+	}
+	var members []*manager.Task
+	for _, name := range []string{"first", "second"} {
+		member := mockTask(name, wants(1, 128)...)
+		member.GroupInfo = manager.GroupInfo{GroupName: "pod", InGroup: true, Executor: executor}
+		members = append(members, member)
+	}
+
+	p := d.Plan()
+	for _, member := range members {
+		if _, err := p.Assign(member); err != nil {
+			t.Fatal(err.Error())
+		}
+	}
+	if p.offers[0].Cpu != 0 || p.offers[0].Mem != 0 {
+		t.Fatal("The executor should have been paid for exactly once")
+	}
+
+	// Nothing is left for another member once the executor is paid for.
+	extra := mockTask("third", wants(0.5, 1)...)
+	extra.GroupInfo = members[0].GroupInfo
+	if _, err := p.Assign(extra); err == nil {
+		t.Fatal("The offer should have been used up by the pod")
+	}
+	if err := p.Commit(); err != nil {
+		t.Fatal(err.Error())
+	}
+}
+
+// Makes sure an offer's disk info follows what's left of it.
+func TestSetResources(t *testing.T) {
+	t.Parallel()
+
+	disk := resources.CreateResource("disk", "*", 100)
+	disk.Disk = &mesos_v1.Resource_DiskInfo{
+		Source: &mesos_v1.Resource_DiskInfo_Source{Type: mesos_v1.Resource_DiskInfo_Source_PATH.Enum()},
+	}
+	offer := &MesosOfferResources{}
+
+	setResources(offer, append(scalars(1, 128), disk))
+	if offer.Cpu != 1 || offer.Mem != 128 || offer.Disk != disk.Disk {
+		t.Fatal("Totals and disk info should match the resources left")
+	}
+
+	setResources(offer, scalars(1, 128))
+	if offer.Disk != nil {
+		t.Fatal("Disk info should be gone once the disk is used up")
+	}
+}
@@ -0,0 +1,219 @@
+// Copyright 2017 Verizon
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package manager
+
+import (
+	"github.com/verizonlabs/mesos-framework-sdk/include/mesos_v1"
+	"github.com/verizonlabs/mesos-framework-sdk/scheduler/strategy"
+	"github.com/verizonlabs/mesos-framework-sdk/task/manager"
+	"strconv"
+)
+
+// Picks which of the offers that fit a task it gets placed on.
+// Returning nil means none of them are acceptable.
+// Placements only know a task is gone once the resource manager's ForgetTask is called for it,
+// so whoever handles status updates has to do that for every task that reaches a terminal state.
+type PlacementStrategy interface {
+	Place(task *manager.Task, candidates []*MesosOfferResources, placements *Placements) *MesosOfferResources
+}
+
+/*
+Placements keeps track of where the instances of each application ended up.
+
+Applications are identified by their group name, or by the task name for tasks outside of a group,
+so every instance of an application is counted together.
+*/
+type Placements struct {
+	agents     map[string]map[string]int        // Application to agent to number of instances.
+	attributes map[string][]*mesos_v1.Attribute // Agent attributes, as seen in their latest offer.
+}
+
+func NewPlacements() *Placements {
+	return &Placements{
+		agents:     make(map[string]map[string]int),
+		attributes: make(map[string][]*mesos_v1.Attribute),
+	}
+}
+
+// Number of instances of the task's application on an agent.
+func (p *Placements) OnAgent(task *manager.Task, agent string) int {
+	return p.agents[application(task)][agent]
+}
+
+// Number of instances of the task's application on agents with the given attribute value.
+func (p *Placements) WithAttribute(task *manager.Task, name, value string) int {
+	count := 0
+	for agent, n := range p.agents[application(task)] {
+		if attributeValue(p.attributes[agent], name) == value {
+			count += n
+		}
+	}
+
+	return count
+}
+
+// Records an instance of the task's application on an agent.
+func (p *Placements) Add(task *manager.Task, agent string) {
+	app := application(task)
+	if p.agents[app] == nil {
+		p.agents[app] = make(map[string]int)
+	}
+	p.agents[app][agent]++
+}
+
+// Forgets an instance of the task's application on an agent.
+func (p *Placements) Remove(task *manager.Task, agent string) {
+	app := application(task)
+	if p.agents[app][agent] <= 1 {
+		delete(p.agents[app], agent)
+		return
+	}
+	p.agents[app][agent]--
+}
+
+// Copies the placements so they can be changed tentatively.
+func (p *Placements) clone() *Placements {
+	c := NewPlacements()
+	for app, agents := range p.agents {
+		c.agents[app] = make(map[string]int, len(agents))
+		for agent, n := range agents {
+			c.agents[app][agent] = n
+		}
+	}
+	for agent, attributes := range p.attributes {
+		c.attributes[agent] = attributes
+	}
+
+	return c
+}
+
+func (p *Placements) observe(offer *mesos_v1.Offer) {
+	p.attributes[offer.GetAgentId().GetValue()] = offer.Attributes
+}
+
+func application(task *manager.Task) string {
+	if task.GroupInfo.GroupName != "" {
+		return task.GroupInfo.GroupName
+	}
+
+	return task.Info.GetName()
+}
+
+func attributeValue(attributes []*mesos_v1.Attribute, name string) string {
+	for _, a := range attributes {
+		if a.GetName() != name {
+			continue
+		}
+
+		switch a.GetType() {
+		case SCALAR:
+			return strconv.FormatFloat(a.GetScalar().GetValue(), 'f', -1, 64)
+		case TEXT:
+			return a.GetText().GetValue()
+		}
+	}
+
+	return ""
+}
+
+// Strategies available by default, keyed by the strategy type tasks ask for.
+func defaultStrategies() map[string]PlacementStrategy {
+	return map[string]PlacementStrategy{
+		strategy.NONE:      FirstFit{},
+		strategy.FIRST_FIT: FirstFit{},
+		strategy.BIN_PACK:  BinPack{},
+		strategy.SPREAD:    Spread{},
+		strategy.UNIQUE:    Unique{},
+		strategy.COLOCATE:  Colocate{},
+	}
+}
+
+// Takes the first offer that fits.
+type FirstFit struct{}
+
+func (FirstFit) Place(task *manager.Task, candidates []*MesosOfferResources, placements *Placements) *MesosOfferResources {
+	if len(candidates) == 0 {
+		return nil
+	}
+
+	return candidates[0]
+}
+
+// Takes the offer with the least cpu left over, then the least memory, to keep agents as full as possible.
+type BinPack struct{}
+
+func (BinPack) Place(task *manager.Task, candidates []*MesosOfferResources, placements *Placements) *MesosOfferResources {
+	var best *MesosOfferResources
+	for _, c := range candidates {
+		if best == nil || c.Cpu < best.Cpu || (c.Cpu == best.Cpu && c.Mem < best.Mem) {
+			best = c
+		}
+	}
+
+	return best
+}
+
+// Takes the offer from the agent, or the attribute value, with the fewest instances of the application.
+type Spread struct{}
+
+func (Spread) Place(task *manager.Task, candidates []*MesosOfferResources, placements *Placements) *MesosOfferResources {
+	var best *MesosOfferResources
+	fewest := 0
+	for _, c := range candidates {
+		count := placements.OnAgent(task, c.Offer.GetAgentId().GetValue())
+		if name := task.Strategy.Attribute; name != "" {
+			count = placements.WithAttribute(task, name, attributeValue(c.Offer.Attributes, name))
+		}
+
+		if best == nil || count < fewest {
+			best, fewest = c, count
+		}
+	}
+
+	return best
+}
+
+// Only takes offers from agents that aren't running an instance of the application yet.
+type Unique struct{}
+
+func (Unique) Place(task *manager.Task, candidates []*MesosOfferResources, placements *Placements) *MesosOfferResources {
+	for _, c := range candidates {
+		if placements.OnAgent(task, c.Offer.GetAgentId().GetValue()) == 0 {
+			return c
+		}
+	}
+
+	return nil
+}
+
+// Takes offers from the agent running the most instances of the application, so they end up together.
+// Only agents already running an instance are acceptable once the first one has been placed.
+type Colocate struct{}
+
+func (Colocate) Place(task *manager.Task, candidates []*MesosOfferResources, placements *Placements) *MesosOfferResources {
+	if len(placements.agents[application(task)]) == 0 {
+		return FirstFit{}.Place(task, candidates, placements)
+	}
+
+	var best *MesosOfferResources
+	most := 0
+	for _, c := range candidates {
+		if count := placements.OnAgent(task, c.Offer.GetAgentId().GetValue()); count > most {
+			best, most = c, count
+		}
+	}
+
+	return best
+}
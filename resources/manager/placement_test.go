@@ -0,0 +1,207 @@
+// Copyright 2017 Verizon
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package manager
+
+import (
+	"github.com/golang/protobuf/proto"
+	"github.com/verizonlabs/mesos-framework-sdk/include/mesos_v1"
+	"github.com/verizonlabs/mesos-framework-sdk/scheduler/strategy"
+	"github.com/verizonlabs/mesos-framework-sdk/task"
+	"testing"
+)
+
+// An offer from an agent in the given zone with cpu and memory left.
+func candidate(id, agent, zone string, cpu, mem float64) *MesosOfferResources {
+	offer := mockOffer(id, agent, scalars(cpu, mem)...)
+	offer.Attributes = []*mesos_v1.Attribute{
+		{
+			Name: proto.String("zone"),
+			Type: mesos_v1.Value_TEXT.Enum(),
+			Text: &mesos_v1.Value_Text{Value: proto.String(zone)},
+		},
+	}
+
+	return &MesosOfferResources{Offer: offer, Cpu: cpu, Mem: mem, Resources: offer.Resources}
+}
+
+// Placements that have seen every candidate's agent.
+func observed(candidates ...*MesosOfferResources) *Placements {
+	p := NewPlacements()
+	for _, c := range candidates {
+		p.observe(c.Offer)
+	}
+
+	return p
+}
+
+// Makes sure the fullest offer is picked, going by memory when cpu is the same.
+func TestBinPack(t *testing.T) {
+	t.Parallel()
+
+	candidates := []*MesosOfferResources{
+		candidate("big", "a", "east", 4, 1024),
+		candidate("small", "b", "east", 1, 512),
+		candidate("smallest", "c", "east", 1, 256),
+	}
+	task := mockTask("app", wants(1, 128)...)
+
+	if (BinPack{}).Place(task, candidates, observed(candidates...)) != candidates[2] {
+		t.Fatal("The offer with the least left over should have been picked")
+	}
+	if (BinPack{}).Place(task, nil, NewPlacements()) != nil {
+		t.Fatal("Nothing should be picked without candidates")
+	}
+}
+
+// Checks that instances go to the agent with the fewest of them.
+func TestSpread(t *testing.T) {
+	t.Parallel()
+
+	candidates := []*MesosOfferResources{
+		candidate("busy", "a", "east", 1, 128),
+		candidate("free", "b", "east", 1, 128),
+	}
+	placements := observed(candidates...)
+	task := mockTask("app", wants(1, 128)...)
+	placements.Add(task, "a")
+
+	if (Spread{}).Place(task, candidates, placements) != candidates[1] {
+		t.Fatal("The agent without an instance should have been picked")
+	}
+
+	// Other applications don't count.
+	placements.Add(mockTask("other", wants(1, 128)...), "b")
+	placements.Add(mockTask("other", wants(1, 128)...), "b")
+	if (Spread{}).Place(task, candidates, placements) != candidates[1] {
+		t.Fatal("Only instances of the same application should be counted")
+	}
+}
+
+// Makes sure instances are spread across the values of an attribute instead of agents when asked to.
+func TestSpread_Attribute(t *testing.T) {
+	t.Parallel()
+
+	candidates := []*MesosOfferResources{
+		candidate("east-1", "a", "east", 1, 128),
+		candidate("east-2", "b", "east", 1, 128),
+		candidate("west", "c", "west", 1, 128),
+	}
+	placements := observed(candidates...)
+	task := mockTask("app", wants(1, 128)...)
+	task.Strategy.Attribute = "zone"
+	placements.Add(task, "a")
+
+	// Agent b has no instances, but it's in the same zone as agent a.
+	if (Spread{}).Place(task, candidates, placements) != candidates[2] {
+		t.Fatal("The zone without an instance should have been picked")
+	}
+	if placements.WithAttribute(task, "zone", "east") != 1 || placements.WithAttribute(task, "zone", "west") != 0 {
+		t.Fatal("Instances were not counted by zone")
+	}
+}
+
+// Checks that agents already running an instance are never picked.
+func TestUnique(t *testing.T) {
+	t.Parallel()
+
+	candidates := []*MesosOfferResources{
+		candidate("taken", "a", "east", 1, 128),
+		candidate("free", "b", "east", 1, 128),
+	}
+	placements := observed(candidates...)
+	task := mockTask("app", wants(1, 128)...)
+	placements.Add(task, "a")
+
+	if (Unique{}).Place(task, candidates, placements) != candidates[1] {
+		t.Fatal("The agent without an instance should have been picked")
+	}
+
+	placements.Add(task, "b")
+	if (Unique{}).Place(task, candidates, placements) != nil {
+		t.Fatal("No agent should be acceptable once every one runs an instance")
+	}
+}
+
+// Makes sure instances end up together once the first one has been placed.
+func TestColocate(t *testing.T) {
+	t.Parallel()
+
+	candidates := []*MesosOfferResources{
+		candidate("first", "a", "east", 1, 128),
+		candidate("second", "b", "east", 1, 128),
+	}
+	placements := observed(candidates...)
+	task := mockTask("app", wants(1, 128)...)
+
+	if (Colocate{}).Place(task, candidates, placements) != candidates[0] {
+		t.Fatal("The first instance should take the first offer that fits")
+	}
+
+	placements.Add(task, "b")
+	if (Colocate{}).Place(task, candidates, placements) != candidates[1] {
+		t.Fatal("Instances should follow the agent already running one")
+	}
+	if (Colocate{}).Place(task, candidates[:1], placements) != nil {
+		t.Fatal("Agents without an instance should not be acceptable once one has been placed")
+	}
+}
+
+// Checks that tasks in a group count as the same application.
+func TestPlacements_Groups(t *testing.T) {
+	t.Parallel()
+
+	placements := NewPlacements()
+	first, second := mockTask("first"), mockTask("second")
+	first.GroupInfo.GroupName, second.GroupInfo.GroupName = "pod", "pod"
+
+	placements.Add(first, "a")
+	if placements.OnAgent(second, "a") != 1 {
+		t.Fatal("Members of a group should be counted together")
+	}
+
+	placements.Remove(first, "a")
+	if placements.OnAgent(second, "a") != 0 {
+		t.Fatal("Removed instances should no longer be counted")
+	}
+}
+
+// Makes sure a task that's assigned again without being forgotten is only counted where it was placed last.
+func TestDefaultResourceManager_Replace(t *testing.T) {
+	t.Parallel()
+
+	d := NewDefaultResourceManagerWithConfig(nil, ResourceManagerConfig{}, l)
+	d.AddOffers([]*mesos_v1.Offer{mockOffer("first", "a", scalars(1, 128)...)})
+
+	app := mockTask("app", wants(1, 128)...)
+	app.Strategy = task.Strategy{Type: strategy.UNIQUE}
+	if _, err := d.Assign(app); err != nil {
+		t.Fatal(err.Error())
+	}
+
+	// The task failed and is being rescheduled, but ForgetTask was never called.
+	d.AddOffers([]*mesos_v1.Offer{mockOffer("second", "b", scalars(1, 128)...)})
+	if _, err := d.Assign(app); err != nil {
+		t.Fatal(err.Error())
+	}
+
+	if d.placements.OnAgent(app, "a") != 0 || d.placements.OnAgent(app, "b") != 1 {
+		t.Fatal("The task should only be counted on the agent it was placed on last")
+	}
+
+	d.ForgetTask(app)
+	if d.placements.OnAgent(app, "b") != 0 {
+		t.Fatal("Forgotten tasks should no longer be counted")
+	}
+}
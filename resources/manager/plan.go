@@ -0,0 +1,272 @@
+// Copyright 2017 Verizon
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package manager
+
+import (
+	"errors"
+	"github.com/verizonlabs/mesos-framework-sdk/include/mesos_v1"
+	"github.com/verizonlabs/mesos-framework-sdk/resources"
+	"github.com/verizonlabs/mesos-framework-sdk/scheduler/strategy"
+	"github.com/verizonlabs/mesos-framework-sdk/task/manager"
+)
+
+/*
+Plan assigns a batch of tasks to offers without changing anything until it's committed.
+
+Each assignment works against the plan's own view of the offers, so later tasks in the batch see what earlier ones
+have used and several tasks can end up sharing an offer. Tasks that share an offer have to be launched together in
+the same accept call. Everything planned on an offer is worked out again with each task added to it, so ports asked
+for by count never take away a port a later task asked for by number, and executors are only paid for once.
+
+Committing applies the whole plan at once, or none of it if the offers have changed underneath it in a way that
+means it no longer fits. Rolling back simply throws the plan away.
+*/
+type Plan struct {
+	manager     *DefaultResourceManager
+	offers      []*MesosOfferResources                        // Our view of the offers, with planned tasks taken out.
+	originals   map[*MesosOfferResources]*MesosOfferResources // Offers held by the manager, keyed by our view of them.
+	strategies  map[string]PlacementStrategy
+	placements  *Placements
+	placed      map[string]string                             // Where tasks were placed before planning, so replanned tasks aren't counted twice.
+	available   map[*MesosOfferResources][]*mesos_v1.Resource // What each offer had when planning started.
+	planned     map[*MesosOfferResources][]*manager.Task      // Tasks planned on each offer so far, in order.
+	assignments []*Assignment
+	done        bool
+}
+
+// A task and the offer it's planned to be launched on.
+type Assignment struct {
+	Task  *manager.Task
+	Offer *mesos_v1.Offer
+	held  *MesosOfferResources
+}
+
+// Starts planning against the offers we hold right now.
+func (d *DefaultResourceManager) Plan() *Plan {
+	d.declineExpired()
+
+	d.lock.Lock()
+	defer d.lock.Unlock()
+
+	p := &Plan{
+		manager:    d,
+		offers:     make([]*MesosOfferResources, 0, len(d.offers)),
+		originals:  make(map[*MesosOfferResources]*MesosOfferResources, len(d.offers)),
+		strategies: make(map[string]PlacementStrategy, len(d.strategies)),
+		placements: d.placements.clone(),
+		placed:     make(map[string]string, len(d.placed)),
+		available:  make(map[*MesosOfferResources][]*mesos_v1.Resource, len(d.offers)),
+		planned:    make(map[*MesosOfferResources][]*manager.Task),
+	}
+	for _, offer := range d.offers {
+		view := *offer
+		p.offers = append(p.offers, &view)
+		p.originals[&view] = offer
+		p.available[&view] = offer.Resources
+	}
+	for name, s := range d.strategies {
+		p.strategies[name] = s
+	}
+	for id, agent := range d.placed {
+		p.placed[id] = agent
+	}
+
+	return p
+}
+
+// Plans which offer a task goes on.
+// The task's strategy decides which of the offers that fit it gets, falling back to the first one that fits
+// if its effort is best-effort and the strategy can't be satisfied.
+// Tasks that ask for a role only get offers allocated to it.
+func (p *Plan) Assign(task *manager.Task) (*mesos_v1.Offer, error) {
+	if p.done {
+		return nil, errors.New("Plan has already been committed or rolled back")
+	}
+
+	var candidates []*MesosOfferResources
+	for _, offer := range p.offers {
+		if task.Role != "" && offer.Role != task.Role {
+			continue
+		}
+		if p.manager.draining(offer) {
+			continue
+		}
+		if _, _, err := p.allocate(offer, task); err != nil {
+			continue
+		}
+		candidates = append(candidates, offer)
+	}
+
+	offer := p.strategy(task).Place(task, candidates, p.placements)
+	if offer == nil && task.Strategy.Effort == strategy.BEST_EFFORT {
+		offer = FirstFit{}.Place(task, candidates, p.placements)
+	}
+	if offer == nil {
+		return nil, errors.New("Cannot find a suitable offer for task " + task.Info.GetName())
+	}
+
+	_, remaining, err := p.allocate(offer, task)
+	if err != nil {
+		return nil, err
+	}
+	setResources(offer, remaining)
+	p.planned[offer] = append(p.planned[offer], task)
+
+	id := task.Info.GetTaskId().GetValue()
+	if agent, ok := p.placed[id]; ok {
+		p.placements.Remove(task, agent)
+	}
+	p.placed[id] = offer.Offer.GetAgentId().GetValue()
+	p.placements.Add(task, offer.Offer.GetAgentId().GetValue())
+	p.assignments = append(p.assignments, &Assignment{
+		Task:  task,
+		Offer: offer.Offer,
+		held:  p.originals[offer],
+	})
+
+	return offer.Offer, nil
+}
+
+// Tasks planned so far, in the order they were assigned.
+func (p *Plan) Assignments() []*Assignment {
+	return p.assignments
+}
+
+/*
+Commit applies every assignment in the plan, or none of them.
+
+Offers that were rescinded, expired or used up by someone else since planning cause the whole plan to fail,
+in which case the offers and tasks are left exactly as they were. Otherwise each task is given exactly the resources
+it was allocated, marked as allocated to the offer's role for multi-role frameworks, and the offers are handled
+as if every task had been assigned one at a time.
+*/
+func (p *Plan) Commit() error {
+	d := p.manager
+	d.lock.Lock()
+	defer d.lock.Unlock()
+
+	if p.done {
+		return errors.New("Plan has already been committed or rolled back")
+	}
+	p.done = true
+
+	// Work everything out against the offers as they are now before touching anything.
+	var held []*MesosOfferResources
+	tasks := make(map[*MesosOfferResources][]*manager.Task)
+	for _, a := range p.assignments {
+		if !d.holds(a.held) {
+			return errors.New("Offer " + a.Offer.GetId().GetValue() + " is no longer available")
+		}
+
+		if _, ok := tasks[a.held]; !ok {
+			held = append(held, a.held)
+		}
+		tasks[a.held] = append(tasks[a.held], a.Task)
+	}
+
+	allocated := make(map[*manager.Task][]*mesos_v1.Resource, len(p.assignments))
+	remaining := make(map[*MesosOfferResources][]*mesos_v1.Resource, len(held))
+	for _, offer := range held {
+		alloc, rem, err := resources.AllocateAll(offer.Resources, wanted(tasks[offer]))
+		if err != nil {
+			return errors.New("Offer " + offer.Offer.GetId().GetValue() + " no longer fits the tasks planned on it: " +
+				err.Error())
+		}
+
+		for i, t := range tasks[offer] {
+			allocated[t] = alloc[i]
+		}
+		remaining[offer] = rem
+	}
+
+	for offer, rem := range remaining {
+		setResources(offer, rem)
+	}
+	for _, a := range p.assignments {
+		a.Task.Requested = requested(a.Task)
+		a.Task.Info.Resources = allocated[a.Task]
+
+		d.use(a.Task, a.held)
+		d.allocate(a.Task, a.held)
+		d.assign(a.Task, a.held)
+		d.place(a.Task, a.Offer.GetAgentId().GetValue())
+	}
+
+	return nil
+}
+
+// Throws the plan away, leaving the offers and tasks untouched.
+func (p *Plan) Rollback() {
+	if p.done {
+		return
+	}
+	p.done = true
+	p.assignments = nil
+}
+
+// Gets the strategy a task asked for, tasks without one or with one we don't know of take the first offer that fits.
+func (p *Plan) strategy(task *manager.Task) PlacementStrategy {
+	if s, ok := p.strategies[task.Strategy.Type]; ok {
+		return s
+	}
+
+	return FirstFit{}
+}
+
+// Works out what's left of an offer with the task added to the ones already planned on it.
+func (p *Plan) allocate(offer *MesosOfferResources, task *manager.Task) ([][]*mesos_v1.Resource, []*mesos_v1.Resource, error) {
+	tasks := append(p.planned[offer][:len(p.planned[offer]):len(p.planned[offer])], task)
+	return resources.AllocateAll(p.available[offer], wanted(tasks))
+}
+
+// Resources wanted by each of the tasks launched on an offer, in the same order,
+// followed by the resources of every executor they need, each counted once.
+func wanted(tasks []*manager.Task) [][]*mesos_v1.Resource {
+	wants := make([][]*mesos_v1.Resource, 0, len(tasks))
+	for _, t := range tasks {
+		wants = append(wants, requested(t))
+	}
+
+	seen := make(map[*mesos_v1.ExecutorInfo]bool)
+	ids := make(map[string]bool)
+	for _, t := range tasks {
+		e := executor(t)
+		if e == nil || seen[e] || ids[e.GetExecutorId().GetValue()] {
+			continue
+		}
+
+		seen[e] = true
+		if id := e.GetExecutorId().GetValue(); id != "" {
+			ids[id] = true
+		}
+		wants = append(wants, e.Resources)
+	}
+
+	return wants
+}
+
+// Replaces what's left of an offer, keeping the totals up to date.
+func setResources(offer *MesosOfferResources, remaining []*mesos_v1.Resource) {
+	offer.Resources = remaining
+	offer.Cpu = resources.Sum(remaining, "cpus")
+	offer.Mem = resources.Sum(remaining, "mem")
+
+	offer.Disk = nil
+	for _, r := range remaining {
+		if r.GetName() == "disk" {
+			offer.Disk = r.GetDisk()
+		}
+	}
+}
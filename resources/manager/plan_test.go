@@ -0,0 +1,223 @@
+// Copyright 2017 Verizon
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package manager
+
+import (
+	"github.com/golang/protobuf/proto"
+	"github.com/verizonlabs/mesos-framework-sdk/include/mesos_v1"
+	"github.com/verizonlabs/mesos-framework-sdk/resources"
+	"github.com/verizonlabs/mesos-framework-sdk/task"
+	"github.com/verizonlabs/mesos-framework-sdk/task/manager"
+	"testing"
+)
+
+// Makes sure a task that only fits part of the way doesn't change the offer.
+func TestPlan_AssignPartialFit(t *testing.T) {
+	t.Parallel()
+
+	d := NewDefaultResourceManagerWithConfig(nil, ResourceManagerConfig{}, l)
+	d.AddOffers([]*mesos_v1.Offer{mockOffer("offer", "agent", scalars(4, 128)...)})
+
+	p := d.Plan()
+	if _, err := p.Assign(mockTask("hungry", wants(1, 256)...)); err == nil {
+		t.Fatal("The task should not fit since there isn't enough memory")
+	}
+	if p.offers[0].Cpu != 4 || p.offers[0].Mem != 128 || len(p.Assignments()) != 0 {
+		t.Fatal("The plan's view of the offer should not have changed")
+	}
+	if err := p.Commit(); err != nil {
+		t.Fatal(err.Error())
+	}
+
+	held := d.byId["offer"][0]
+	if held.Cpu != 4 || held.Mem != 128 || resources.Sum(held.Resources, "cpus") != 4 || len(d.Offers()) != 1 {
+		t.Fatal("The offer should have been left as it was")
+	}
+}
+
+// Checks that rolling back leaves the offers, tasks and placements as they were.
+func TestPlan_Rollback(t *testing.T) {
+	t.Parallel()
+
+	d := NewDefaultResourceManagerWithConfig(nil, ResourceManagerConfig{}, l)
+	d.AddOffers([]*mesos_v1.Offer{mockOffer("offer", "agent", scalars(2, 256)...)})
+
+	first := mockTask("app", wants(1, 128)...)
+	asked := first.Info.Resources
+	p := d.Plan()
+	if _, err := p.Assign(first); err != nil {
+		t.Fatal(err.Error())
+	}
+	p.Rollback()
+
+	if first.Info.Resources[0] != asked[0] || first.Requested != nil {
+		t.Fatal("The task should still be asking for what it originally requested")
+	}
+	if d.placements.OnAgent(first, "agent") != 0 || len(d.placed) != 0 {
+		t.Fatal("Nothing should have been placed")
+	}
+	if held := d.byId["offer"][0]; held.Cpu != 2 || held.Mem != 256 || len(d.assigned) != 0 {
+		t.Fatal("The offer should have been left as it was")
+	}
+
+	if _, err := p.Assign(first); err == nil {
+		t.Fatal("Rolled back plans should not take any more tasks")
+	}
+	if err := p.Commit(); err == nil {
+		t.Fatal("Rolled back plans should not be committed")
+	}
+
+	// Everything is still there for the next plan.
+	if _, err := d.AssignAll([]*manager.Task{first, mockTask("app", wants(1, 128)...)}); err != nil {
+		t.Fatal(err.Error())
+	}
+}
+
+// Makes sure committing fails without changing anything once an offer has been rescinded.
+func TestPlan_CommitAfterRescind(t *testing.T) {
+	t.Parallel()
+
+	d := NewDefaultResourceManagerWithConfig(nil, ResourceManagerConfig{}, l)
+	d.AddOffers([]*mesos_v1.Offer{
+		mockOffer("kept", "a", scalars(1, 128)...),
+		mockOffer("rescinded", "b", scalars(1, 128)...),
+	})
+
+	first, second := mockTask("first", wants(1, 128)...), mockTask("second", wants(1, 128)...)
+	p := d.Plan()
+	for _, task := range []*manager.Task{first, second} {
+		if _, err := p.Assign(task); err != nil {
+			t.Fatal(err.Error())
+		}
+	}
+
+	d.Rescind(p.Assignments()[1].Offer.Id)
+	if err := p.Commit(); err == nil {
+		t.Fatal("The plan should not be committed once one of its offers is gone")
+	}
+
+	if first.Requested != nil || second.Requested != nil || len(d.assigned) != 0 || len(d.placed) != 0 {
+		t.Fatal("None of the tasks should have been assigned")
+	}
+	if offers := d.Offers(); len(offers) != 1 || offers[0].GetId().GetValue() != "kept" {
+		t.Fatal("The offer that's still held should be left alone")
+	}
+}
+
+// Checks that committing fails when another assignment used the offer after planning.
+func TestPlan_CommitAfterAssign(t *testing.T) {
+	t.Parallel()
+
+	d := NewDefaultResourceManagerWithConfig(nil, ResourceManagerConfig{}, l)
+	d.AddOffers([]*mesos_v1.Offer{mockOffer("offer", "agent", scalars(1, 128)...)})
+
+	planned := mockTask("planned", wants(1, 128)...)
+	p := d.Plan()
+	if _, err := p.Assign(planned); err != nil {
+		t.Fatal(err.Error())
+	}
+
+	if _, err := d.Assign(mockTask("sneaky", wants(1, 128)...)); err != nil {
+		t.Fatal(err.Error())
+	}
+	if err := p.Commit(); err == nil {
+		t.Fatal("The plan should not be committed once its offer was used by someone else")
+	}
+	if planned.Requested != nil || len(d.assigned["offer"]) != 1 {
+		t.Fatal("Only the task assigned first should have the offer")
+	}
+}
+
+// Makes sure committing fails when the offer is still held but no longer has room for the plan.
+func TestPlan_CommitNoLongerFits(t *testing.T) {
+	t.Parallel()
+
+	d := NewDefaultResourceManagerWithConfig(nil, ResourceManagerConfig{}, l)
+	d.AddOffers([]*mesos_v1.Offer{mockOffer("offer", "agent", scalars(2, 256)...)})
+
+	planned := mockTask("planned", wants(2, 256)...)
+	p := d.Plan()
+	if _, err := p.Assign(planned); err != nil {
+		t.Fatal(err.Error())
+	}
+
+	// Tasks with filters that match the offer leave it held so that it can be shared.
+	filtered := mockTask("filtered", wants(1, 128)...)
+	filtered.Filters = []task.Filter{{Type: "TEXT", Value: []string{"rack"}}}
+	d.byId["offer"][0].Offer.Attributes = []*mesos_v1.Attribute{
+		{
+			Name: proto.String("rack"),
+			Type: mesos_v1.Value_TEXT.Enum(),
+			Text: &mesos_v1.Value_Text{Value: proto.String("rack")},
+		},
+	}
+	if _, err := d.Assign(filtered); err != nil {
+		t.Fatal(err.Error())
+	}
+
+	if err := p.Commit(); err == nil {
+		t.Fatal("The plan should not be committed once its offer no longer fits")
+	}
+	if planned.Requested != nil {
+		t.Fatal("The planned task should not have been assigned")
+	}
+	if held := d.byId["offer"][0]; held.Cpu != 1 || held.Mem != 128 {
+		t.Fatal("Only the filtered task should have been taken out of the offer")
+	}
+}
+
+// Checks that several tasks can share an offer and each get exactly what they asked for.
+func TestPlan_SharedOffer(t *testing.T) {
+	t.Parallel()
+
+	d := NewDefaultResourceManagerWithConfig(nil, ResourceManagerConfig{}, l)
+	d.AddOffers([]*mesos_v1.Offer{mockOffer("offer", "agent", scalars(3, 384)...)})
+
+	var tasks []*manager.Task
+	p := d.Plan()
+	for _, name := range []string{"first", "second", "third"} {
+		task := mockTask(name, wants(1, 128)...)
+		offer, err := p.Assign(task)
+		if err != nil {
+			t.Fatal(err.Error())
+		}
+		if offer.GetId().GetValue() != "offer" {
+			t.Fatal("Every task should share the same offer")
+		}
+		tasks = append(tasks, task)
+	}
+
+	if _, err := p.Assign(mockTask("fourth", wants(1, 128)...)); err == nil {
+		t.Fatal("Nothing should be left for a fourth task")
+	}
+	if err := p.Commit(); err != nil {
+		t.Fatal(err.Error())
+	}
+
+	for _, task := range tasks {
+		if resources.Sum(task.Info.Resources, "cpus") != 1 || resources.Sum(task.Info.Resources, "mem") != 128 {
+			t.Fatal("Every task should get exactly what it asked for")
+		}
+		if task.Info.Resources[0].GetRole() != "*" {
+			t.Fatal("Tasks should be given the resources as they were offered")
+		}
+		if d.placements.OnAgent(task, "agent") != 1 {
+			t.Fatal("Every task should be placed on the offer's agent")
+		}
+	}
+	if len(d.assigned["offer"]) != 3 || d.HasResources() {
+		t.Fatal("The offer should be used up by all three tasks")
+	}
+}
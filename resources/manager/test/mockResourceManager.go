@@ -43,12 +43,47 @@ func (m MockResourceManager) Assign(task *manager.Task) (*mesos_v1.Offer, error)
 	return &mesos_v1.Offer{}, nil
 }
 
+func (m MockResourceManager) AssignAll(tasks []*manager.Task) ([]*mesos_v1.Offer, error) {
+	offers := make([]*mesos_v1.Offer, 0, len(tasks))
+	for range tasks {
+		offers = append(offers, &mesos_v1.Offer{})
+	}
+
+	return offers, nil
+}
+
 func (m MockResourceManager) Offers() []*mesos_v1.Offer {
 	return []*mesos_v1.Offer{
 		{},
 	}
 }
 
+func (m MockResourceManager) OffersByRole() map[string][]*mesos_v1.Offer {
+	return map[string][]*mesos_v1.Offer{
+		"": {{}},
+	}
+}
+
+func (m MockResourceManager) Rescind(offerId *mesos_v1.OfferID) []*manager.Task {
+	return nil
+}
+
+func (m MockResourceManager) AcceptFailed(offerId *mesos_v1.OfferID) []*manager.Task {
+	return nil
+}
+
+func (m MockResourceManager) DeclineUnused() {
+
+}
+
+func (m MockResourceManager) TrackTask(task *manager.Task) {
+
+}
+
+func (m MockResourceManager) ForgetTask(task *manager.Task) {
+
+}
+
 type MockBrokenResourceManager struct{}
 
 func (m MockBrokenResourceManager) AddOffers(offers []*mesos_v1.Offer) {
@@ -71,8 +106,36 @@ func (m MockBrokenResourceManager) Assign(task *mesos_v1.TaskInfo) (*mesos_v1.Of
 	return nil, errors.New("Broken.")
 }
 
+func (m MockBrokenResourceManager) AssignAll(tasks []*manager.Task) ([]*mesos_v1.Offer, error) {
+	return nil, errors.New("Broken.")
+}
+
 func (m MockBrokenResourceManager) Offers() []*mesos_v1.Offer {
 	return []*mesos_v1.Offer{
 		{},
 	}
 }
+
+func (m MockBrokenResourceManager) OffersByRole() map[string][]*mesos_v1.Offer {
+	return map[string][]*mesos_v1.Offer{}
+}
+
+func (m MockBrokenResourceManager) Rescind(offerId *mesos_v1.OfferID) []*manager.Task {
+	return nil
+}
+
+func (m MockBrokenResourceManager) AcceptFailed(offerId *mesos_v1.OfferID) []*manager.Task {
+	return nil
+}
+
+func (m MockBrokenResourceManager) DeclineUnused() {
+
+}
+
+func (m MockBrokenResourceManager) TrackTask(task *manager.Task) {
+
+}
+
+func (m MockBrokenResourceManager) ForgetTask(task *manager.Task) {
+
+}
@@ -17,6 +17,7 @@ package resources
 // This package contains helper methods for creating mesos types.
 import (
 	"errors"
+	"github.com/golang/protobuf/proto"
 	"github.com/verizonlabs/mesos-framework-sdk/include/mesos_v1"
 	"github.com/verizonlabs/mesos-framework-sdk/task"
 	"github.com/verizonlabs/mesos-framework-sdk/utils"
@@ -82,6 +83,30 @@ func CreateResource(name, role string, value float64) *mesos_v1.Resource {
 	return resource
 }
 
+// Creates a scalar resource allocated to the given role, which is how multi-role frameworks tell Mesos
+// which of their roles a resource is consumed from.
+// The legacy role field is left alone since it only describes reservations now.
+func CreateAllocatedResource(name, role string, value float64) *mesos_v1.Resource {
+	resource := CreateResource(name, "", value)
+	resource.AllocationInfo = &mesos_v1.Resource_AllocationInfo{Role: utils.ProtoString(role)}
+
+	return resource
+}
+
+// Sets the allocation role on each resource.
+// Mesos rejects launches from multi-role frameworks where this doesn't match the offer the resources came from.
+func AllocateResources(role string, res ...*mesos_v1.Resource) {
+	for _, r := range res {
+		r.AllocationInfo = &mesos_v1.Resource_AllocationInfo{Role: utils.ProtoString(role)}
+	}
+}
+
+// Gets the role a resource was allocated to.
+// Resources offered to frameworks that aren't multi-role capable have no allocation info, in which case this is empty.
+func AllocationRole(res *mesos_v1.Resource) string {
+	return res.GetAllocationInfo().GetRole()
+}
+
 // Creates a disk based on given task.Disk struct.
 func CreateDisk(disk task.Disk, role string) (*mesos_v1.Resource, error) {
 
@@ -220,3 +245,91 @@ func LaunchOfferOperation(taskList []*mesos_v1.TaskInfo) *mesos_v1.Offer_Operati
 		Launch: &mesos_v1.Offer_Operation_Launch{TaskInfos: taskList},
 	}
 }
+
+// Creates a scalar resource that's dynamically reserved for the given role.
+// Labels let us recognize our reservations when they come back in later offers.
+func CreateReservedResource(name, role, principal string, value float64, labels *mesos_v1.Labels) *mesos_v1.Resource {
+	resource := CreateResource(name, role, value)
+	resource.Reservation = &mesos_v1.Resource_ReservationInfo{
+		Principal: utils.ProtoString(principal),
+		Labels:    labels,
+	}
+
+	return resource
+}
+
+// Turns a reserved disk resource into a persistent volume mounted at the container path.
+// The original resource is left untouched.
+func CreatePersistentVolume(disk *mesos_v1.Resource, persistenceId, principal, containerPath string) *mesos_v1.Resource {
+	volume := proto.Clone(disk).(*mesos_v1.Resource)
+	volume.Disk = &mesos_v1.Resource_DiskInfo{
+		Persistence: &mesos_v1.Resource_DiskInfo_Persistence{
+			Id:        utils.ProtoString(persistenceId),
+			Principal: utils.ProtoString(principal),
+		},
+		Volume: &mesos_v1.Volume{
+			Mode:          mesos_v1.Volume_RW.Enum(),
+			ContainerPath: utils.ProtoString(containerPath),
+		},
+	}
+
+	return volume
+}
+
+func ReserveOfferOperation(res []*mesos_v1.Resource) *mesos_v1.Offer_Operation {
+	return &mesos_v1.Offer_Operation{
+		Type:    mesos_v1.Offer_Operation_RESERVE.Enum(),
+		Reserve: &mesos_v1.Offer_Operation_Reserve{Resources: res},
+	}
+}
+
+func UnreserveOfferOperation(res []*mesos_v1.Resource) *mesos_v1.Offer_Operation {
+	return &mesos_v1.Offer_Operation{
+		Type:      mesos_v1.Offer_Operation_UNRESERVE.Enum(),
+		Unreserve: &mesos_v1.Offer_Operation_Unreserve{Resources: res},
+	}
+}
+
+// Volumes must be created from disk resources that are already reserved.
+func CreateVolumesOfferOperation(volumes []*mesos_v1.Resource) *mesos_v1.Offer_Operation {
+	return &mesos_v1.Offer_Operation{
+		Type:   mesos_v1.Offer_Operation_CREATE.Enum(),
+		Create: &mesos_v1.Offer_Operation_Create{Volumes: volumes},
+	}
+}
+
+// Destroying a volume deletes its data and leaves behind the reserved disk it was created from.
+func DestroyVolumesOfferOperation(volumes []*mesos_v1.Resource) *mesos_v1.Offer_Operation {
+	return &mesos_v1.Offer_Operation{
+		Type:    mesos_v1.Offer_Operation_DESTROY.Enum(),
+		Destroy: &mesos_v1.Offer_Operation_Destroy{Volumes: volumes},
+	}
+}
+
+// Creates an executor that's provided by Mesos for running task groups.
+// Every task in the group shares the executor's container, including its network namespace.
+func CreateDefaultExecutorInfo(
+	id *mesos_v1.ExecutorID,
+	frameworkId *mesos_v1.FrameworkID,
+	res []*mesos_v1.Resource,
+	con *mesos_v1.ContainerInfo) *mesos_v1.ExecutorInfo {
+
+	return &mesos_v1.ExecutorInfo{
+		Type:        mesos_v1.ExecutorInfo_DEFAULT.Enum(),
+		ExecutorId:  id,
+		FrameworkId: frameworkId,
+		Resources:   res,
+		Container:   con,
+	}
+}
+
+// Tasks in a group are launched atomically, either all of them start or none do.
+func LaunchGroupOfferOperation(executor *mesos_v1.ExecutorInfo, taskList []*mesos_v1.TaskInfo) *mesos_v1.Offer_Operation {
+	return &mesos_v1.Offer_Operation{
+		Type: mesos_v1.Offer_Operation_LAUNCH_GROUP.Enum(),
+		LaunchGroup: &mesos_v1.Offer_Operation_LaunchGroup{
+			Executor:  executor,
+			TaskGroup: &mesos_v1.TaskGroupInfo{Tasks: taskList},
+		},
+	}
+}
@@ -0,0 +1,262 @@
+// Copyright 2017 Verizon
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package scheduler
+
+import (
+	"context"
+	"github.com/verizonlabs/mesos-framework-sdk/include/mesos_v1"
+	"github.com/verizonlabs/mesos-framework-sdk/logging"
+	"sync"
+	"time"
+)
+
+/*
+AcknowledgementManager takes care of acknowledging status updates once they've been handled.
+
+Updates are only acknowledged after the handler succeeds, so Mesos keeps resending anything we failed to process.
+Updates are tracked by their UUID, which lets us skip handling the same update twice when it's redelivered,
+such as after a failover or when our acknowledgement got lost along the way.
+Failed acknowledgements are retried in the background with exponential backoff.
+*/
+type AcknowledgementManager struct {
+	scheduler  Scheduler
+	config     AcknowledgementConfig
+	logger     logging.Logger
+	pending    map[string]*pendingAck
+	acked      map[string]bool
+	remembered []string // Acknowledged UUIDs in order, so the oldest can be forgotten.
+	closed     bool
+	lock       sync.Mutex
+}
+
+// Retry settings for acknowledgements.
+// Each update gets 5 attempts backing off from 500ms to 30 seconds, and the last 10000 acknowledged updates are
+// remembered to filter out duplicates.
+type AcknowledgementConfig struct {
+	MaxAttempts    int           // Attempts at acknowledging an update before waiting for Mesos to resend it.
+	InitialBackoff time.Duration // Delay before retrying a failed acknowledgement.
+	MaxBackoff     time.Duration
+	Remember       int // Number of acknowledged updates remembered for deduplication.
+}
+
+// Handles a single status update, returning an error if it should not be acknowledged.
+type UpdateHandler func(*mesos_v1.TaskStatus) error
+
+// Describes an update that has been handled but not acknowledged yet.
+type PendingAcknowledgement struct {
+	AgentId   *mesos_v1.AgentID
+	TaskId    *mesos_v1.TaskID
+	Uuid      []byte
+	State     mesos_v1.TaskState
+	Attempts  int
+	LastError error
+}
+
+type pendingAck struct {
+	PendingAcknowledgement
+	handled  bool // False while the handler is still running.
+	inFlight bool // True while an acknowledgement is being sent.
+	backoff  time.Duration
+	timer    *time.Timer
+}
+
+func NewAcknowledgementManager(s Scheduler, config AcknowledgementConfig, logger logging.Logger) *AcknowledgementManager {
+	if config.MaxAttempts <= 0 {
+		config.MaxAttempts = 5
+	}
+	if config.InitialBackoff <= 0 {
+		config.InitialBackoff = 500 * time.Millisecond
+	}
+	if config.MaxBackoff < config.InitialBackoff {
+		config.MaxBackoff = 30 * time.Second
+	}
+	if config.Remember <= 0 {
+		config.Remember = 10000
+	}
+
+	return &AcknowledgementManager{
+		scheduler: s,
+		config:    config,
+		logger:    logger,
+		pending:   make(map[string]*pendingAck),
+		acked:     make(map[string]bool),
+	}
+}
+
+// Runs the handler for a status update and acknowledges it if the handler succeeds.
+// Updates without a UUID are handled but never acknowledged since Mesos doesn't expect it.
+// Redelivered updates are acknowledged again without running the handler.
+func (a *AcknowledgementManager) Handle(status *mesos_v1.TaskStatus, handler UpdateHandler) error {
+	uuid := status.GetUuid()
+	if uuid == nil {
+		return handler(status)
+	}
+	key := string(uuid)
+
+	a.lock.Lock()
+	if a.acked[key] {
+		a.lock.Unlock()
+		a.logger.Emit(logging.INFO, "Update for task %s was already handled, acknowledging it again", status.GetTaskId().GetValue())
+		a.acknowledgeAgain(status)
+		return nil
+	}
+
+	if ack, ok := a.pending[key]; ok {
+
+		// We gave up on acknowledging this update earlier, so start trying again now that Mesos resent it.
+		retry := ack.handled && !ack.inFlight && ack.timer == nil
+		if retry {
+			ack.inFlight = true
+			ack.Attempts = 0
+			ack.backoff = a.config.InitialBackoff
+		}
+		a.lock.Unlock()
+
+		if retry {
+			a.acknowledge(key)
+		} else {
+			a.logger.Emit(logging.INFO, "Update for task %s is already being handled", status.GetTaskId().GetValue())
+		}
+
+		return nil
+	}
+
+	ack := &pendingAck{
+		PendingAcknowledgement: PendingAcknowledgement{
+			AgentId: status.GetAgentId(),
+			TaskId:  status.GetTaskId(),
+			Uuid:    uuid,
+			State:   status.GetState(),
+		},
+		backoff: a.config.InitialBackoff,
+	}
+	a.pending[key] = ack
+	a.lock.Unlock()
+
+	if err := handler(status); err != nil {
+
+		// Mesos will send the update again since we never acknowledged it.
+		a.lock.Lock()
+		delete(a.pending, key)
+		a.lock.Unlock()
+
+		return err
+	}
+
+	a.lock.Lock()
+	ack.handled = true
+	ack.inFlight = true
+	a.lock.Unlock()
+
+	a.acknowledge(key)
+
+	return nil
+}
+
+// Gets all updates that have been handled but haven't been acknowledged yet.
+func (a *AcknowledgementManager) Pending() []PendingAcknowledgement {
+	a.lock.Lock()
+	defer a.lock.Unlock()
+
+	pending := make([]PendingAcknowledgement, 0, len(a.pending))
+	for _, ack := range a.pending {
+		if ack.handled {
+			pending = append(pending, ack.PendingAcknowledgement)
+		}
+	}
+
+	return pending
+}
+
+// Stops retrying failed acknowledgements.
+func (a *AcknowledgementManager) Close() {
+	a.lock.Lock()
+	defer a.lock.Unlock()
+
+	a.closed = true
+	for _, ack := range a.pending {
+		if ack.timer != nil {
+			ack.timer.Stop()
+		}
+	}
+}
+
+// Sends the acknowledgement for a handled update, scheduling a retry if it fails.
+func (a *AcknowledgementManager) acknowledge(key string) {
+	a.lock.Lock()
+	ack, ok := a.pending[key]
+	if !ok || a.closed {
+		if ok {
+			ack.inFlight = false
+		}
+		a.lock.Unlock()
+		return
+	}
+	ack.timer = nil
+	ack.inFlight = true
+	ack.Attempts++
+	a.lock.Unlock()
+
+	resp, err := a.scheduler.AcknowledgeContext(context.Background(), ack.AgentId, ack.TaskId, ack.Uuid)
+	closeBody(resp)
+
+	a.lock.Lock()
+	defer a.lock.Unlock()
+
+	ack.inFlight = false
+
+	if err == nil {
+		delete(a.pending, key)
+		a.remember(key)
+		return
+	}
+
+	ack.LastError = err
+	if ack.Attempts >= a.config.MaxAttempts || a.closed {
+		a.logger.Emit(logging.ERROR, "Giving up on acknowledging update for task %s after %d attempts, waiting for Mesos to resend it",
+			ack.TaskId.GetValue(), ack.Attempts)
+		return
+	}
+
+	a.logger.Emit(logging.ERROR, "Failed to acknowledge update for task %s, retrying in %v: %s", ack.TaskId.GetValue(), ack.backoff, err.Error())
+	ack.timer = time.AfterFunc(ack.backoff, func() {
+		a.acknowledge(key)
+	})
+
+	ack.backoff *= 2
+	if ack.backoff > a.config.MaxBackoff {
+		ack.backoff = a.config.MaxBackoff
+	}
+}
+
+// Acknowledges a redelivered update whose previous acknowledgement must have been lost.
+func (a *AcknowledgementManager) acknowledgeAgain(status *mesos_v1.TaskStatus) {
+	resp, err := a.scheduler.AcknowledgeContext(context.Background(), status.GetAgentId(), status.GetTaskId(), status.GetUuid())
+	closeBody(resp)
+	if err != nil {
+		a.logger.Emit(logging.ERROR, "Failed to acknowledge update for task %s: %s", status.GetTaskId().GetValue(), err.Error())
+	}
+}
+
+// Remembers an acknowledged update, forgetting the oldest one once we're at capacity.
+func (a *AcknowledgementManager) remember(key string) {
+	if len(a.remembered) >= a.config.Remember {
+		delete(a.acked, a.remembered[0])
+		a.remembered = a.remembered[1:]
+	}
+
+	a.acked[key] = true
+	a.remembered = append(a.remembered, key)
+}
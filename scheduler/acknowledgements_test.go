@@ -0,0 +1,185 @@
+// Copyright 2017 Verizon
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package scheduler
+
+import (
+	"errors"
+	"github.com/golang/protobuf/proto"
+	"github.com/verizonlabs/mesos-framework-sdk/include/mesos_v1"
+	"github.com/verizonlabs/mesos-framework-sdk/include/mesos_v1_scheduler"
+	"github.com/verizonlabs/mesos-framework-sdk/task/manager"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// Fails a number of calls before letting them through.
+func failing(failures int32) func(*mesos_v1_scheduler.Call) error {
+	return func(*mesos_v1_scheduler.Call) error {
+		if atomic.AddInt32(&failures, -1) >= 0 {
+			return errors.New("Master unavailable")
+		}
+
+		return nil
+	}
+}
+
+func statusUpdate(task, uuid string) *mesos_v1.TaskStatus {
+	return &mesos_v1.TaskStatus{
+		TaskId:  &mesos_v1.TaskID{Value: proto.String(task)},
+		AgentId: &mesos_v1.AgentID{Value: proto.String("agent")},
+		State:   manager.RUNNING.Enum(),
+		Uuid:    []byte(uuid),
+	}
+}
+
+// Makes sure updates are acknowledged once and only after they've been handled.
+func TestAcknowledgementManager_Handle(t *testing.T) {
+	t.Parallel()
+
+	r := new(recordingClient)
+	a := NewAcknowledgementManager(NewDefaultScheduler(r, i, l), AcknowledgementConfig{}, l)
+	defer a.Close()
+
+	handled := 0
+	handler := func(*mesos_v1.TaskStatus) error {
+		handled++
+		return nil
+	}
+
+	if err := a.Handle(statusUpdate("task", "uuid"), handler); err != nil {
+		t.Fatal(err.Error())
+	}
+
+	acks := r.byType(mesos_v1_scheduler.Call_ACKNOWLEDGE)
+	if handled != 1 || len(acks) != 1 || string(acks[0].Acknowledge.Uuid) != "uuid" {
+		t.Fatal("Update should have been handled and acknowledged")
+	}
+
+	// Redelivered after a failover.
+	a.Handle(statusUpdate("task", "uuid"), handler)
+	if handled != 1 || len(r.byType(mesos_v1_scheduler.Call_ACKNOWLEDGE)) != 2 {
+		t.Fatal("Duplicate update should be acknowledged again without being handled")
+	}
+
+	a.Handle(&mesos_v1.TaskStatus{TaskId: &mesos_v1.TaskID{Value: proto.String("task")}}, handler)
+	if handled != 2 || len(r.byType(mesos_v1_scheduler.Call_ACKNOWLEDGE)) != 2 {
+		t.Fatal("Updates without a UUID should be handled but never acknowledged")
+	}
+}
+
+// Ensures failed handlers leave the update unacknowledged so that Mesos resends it.
+func TestAcknowledgementManager_HandleError(t *testing.T) {
+	t.Parallel()
+
+	r := new(recordingClient)
+	a := NewAcknowledgementManager(NewDefaultScheduler(r, i, l), AcknowledgementConfig{}, l)
+	defer a.Close()
+
+	err := a.Handle(statusUpdate("task", "uuid"), func(*mesos_v1.TaskStatus) error {
+		return errors.New("Broken")
+	})
+	if err == nil || len(r.byType(mesos_v1_scheduler.Call_ACKNOWLEDGE)) != 0 {
+		t.Fatal("Update should not have been acknowledged")
+	}
+
+	a.Handle(statusUpdate("task", "uuid"), func(*mesos_v1.TaskStatus) error {
+		return nil
+	})
+	if len(r.byType(mesos_v1_scheduler.Call_ACKNOWLEDGE)) != 1 {
+		t.Fatal("Resent update should have been handled and acknowledged")
+	}
+}
+
+// Checks that failed acknowledgements are retried and show up as pending until they succeed.
+func TestAcknowledgementManager_Retry(t *testing.T) {
+	t.Parallel()
+
+	f := &recordingClient{onCall: failing(2)}
+	a := NewAcknowledgementManager(NewDefaultScheduler(f, i, l), AcknowledgementConfig{
+		InitialBackoff: 20 * time.Millisecond,
+	}, l)
+	defer a.Close()
+
+	a.Handle(statusUpdate("task", "uuid"), func(*mesos_v1.TaskStatus) error {
+		return nil
+	})
+
+	pending := a.Pending()
+	if len(pending) != 1 || pending[0].TaskId.GetValue() != "task" || pending[0].LastError == nil {
+		t.Fatal("Failed acknowledgement should be pending")
+	}
+
+	for len(a.Pending()) != 0 {
+		time.Sleep(time.Millisecond)
+	}
+
+	if len(f.byType(mesos_v1_scheduler.Call_ACKNOWLEDGE)) != 3 {
+		t.Fatal("Acknowledgement should have succeeded on the third attempt")
+	}
+}
+
+// Makes sure an update redelivered while it's being acknowledged isn't acknowledged a second time.
+func TestAcknowledgementManager_HandleInFlight(t *testing.T) {
+	t.Parallel()
+
+	sending := make(chan struct{})
+	release := make(chan struct{})
+	var calls int32
+	r := &recordingClient{onCall: func(*mesos_v1_scheduler.Call) error {
+		if atomic.AddInt32(&calls, 1) == 1 {
+			close(sending)
+			<-release
+		}
+
+		return nil
+	}}
+	a := NewAcknowledgementManager(NewDefaultScheduler(r, i, l), AcknowledgementConfig{}, l)
+	defer a.Close()
+
+	handler := func(*mesos_v1.TaskStatus) error {
+		return nil
+	}
+
+	done := make(chan error)
+	go func() {
+		done <- a.Handle(statusUpdate("task", "uuid"), handler)
+	}()
+
+	<-sending
+	a.Handle(statusUpdate("task", "uuid"), handler)
+	close(release)
+	if err := <-done; err != nil {
+		t.Fatal(err.Error())
+	}
+
+	if len(r.byType(mesos_v1_scheduler.Call_ACKNOWLEDGE)) != 1 || len(a.Pending()) != 0 {
+		t.Fatal("Update should only have been acknowledged once")
+	}
+}
+
+// Measures performance of handling and acknowledging an update.
+func BenchmarkAcknowledgementManager_Handle(b *testing.B) {
+	a := NewAcknowledgementManager(NewDefaultScheduler(c, i, l), AcknowledgementConfig{}, l)
+	status := statusUpdate("task", "uuid")
+	handler := func(*mesos_v1.TaskStatus) error {
+		return nil
+	}
+	b.ResetTimer()
+
+	for n := 0; n < b.N; n++ {
+		a.Handle(status, handler)
+	}
+}
@@ -0,0 +1,261 @@
+// Copyright 2017 Verizon
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package scheduler
+
+import (
+	"bytes"
+	"context"
+	"github.com/golang/protobuf/proto"
+	"github.com/verizonlabs/mesos-framework-sdk/include/mesos_v1"
+	"github.com/verizonlabs/mesos-framework-sdk/logging"
+	"io/ioutil"
+	"net/http"
+	"sync"
+	"time"
+)
+
+/*
+BatchingScheduler cuts down on the number of calls made during heavy churn.
+
+Declines that arrive within a short window and share the same filters are merged into a single DECLINE call.
+Acknowledgements are handed off to a bounded pool of workers so that the event loop isn't held up by each round trip.
+Once the queue of pending acknowledgements is full, callers block until there's room again.
+
+Both Decline and Acknowledge return as soon as the call has been queued, with a placeholder 202 Accepted response
+standing in for the one Mesos will give us later, so callers can treat it like any other response.
+Failures are logged since Mesos will resend any offers and status updates that we fail to act on.
+All other calls go straight to the wrapped scheduler.
+*/
+type BatchingScheduler struct {
+	Scheduler
+	config   BatchingConfig
+	logger   logging.Logger
+	declines map[string]*declineBatch
+	closed   bool
+	lock     sync.Mutex
+	pending  sync.WaitGroup // Decline batches that haven't been sent yet.
+
+	acks      chan acknowledgement
+	ackClosed bool
+	ackLock   sync.RWMutex
+	workers   sync.WaitGroup
+}
+
+// How long declines wait to be merged and how many acknowledgements are in flight.
+// Declines are held for 100ms or until 1000 of them pile up, and 4 workers drain a queue of up to 1000 acknowledgements.
+type BatchingConfig struct {
+	DeclineWindow time.Duration // How long declines are held before being sent.
+	MaxDeclines   int           // Batches are sent early once they hold this many offers.
+	AckWorkers    int           // Number of acknowledgements sent concurrently.
+	AckQueue      int           // Number of acknowledgements that can wait before callers block, negative for none.
+}
+
+type declineBatch struct {
+	filters  *mesos_v1.Filters
+	offerIds []*mesos_v1.OfferID
+	seen     map[string]bool
+	timer    *time.Timer
+}
+
+type acknowledgement struct {
+	agentId *mesos_v1.AgentID
+	taskId  *mesos_v1.TaskID
+	uuid    []byte
+}
+
+// Wraps a scheduler so that declines are coalesced and acknowledgements are pipelined.
+func NewBatchingScheduler(s Scheduler, config BatchingConfig, logger logging.Logger) *BatchingScheduler {
+	if config.DeclineWindow <= 0 {
+		config.DeclineWindow = 100 * time.Millisecond
+	}
+	if config.MaxDeclines <= 0 {
+		config.MaxDeclines = 1000
+	}
+	if config.AckWorkers <= 0 {
+		config.AckWorkers = 4
+	}
+	if config.AckQueue < 0 {
+		config.AckQueue = 0
+	} else if config.AckQueue == 0 {
+		config.AckQueue = 1000
+	}
+
+	b := &BatchingScheduler{
+		Scheduler: s,
+		config:    config,
+		logger:    logger,
+		declines:  make(map[string]*declineBatch),
+		acks:      make(chan acknowledgement, config.AckQueue),
+	}
+
+	b.workers.Add(config.AckWorkers)
+	for i := 0; i < config.AckWorkers; i++ {
+		go b.acknowledge()
+	}
+
+	return b
+}
+
+// Queues offers to be declined along with any others that share the same filters.
+func (b *BatchingScheduler) Decline(offerIds []*mesos_v1.OfferID, filters *mesos_v1.Filters) (*http.Response, error) {
+	return b.DeclineContext(context.Background(), offerIds, filters)
+}
+
+// The context is only used once the scheduler is closed and declines are no longer batched.
+func (b *BatchingScheduler) DeclineContext(ctx context.Context, offerIds []*mesos_v1.OfferID, filters *mesos_v1.Filters) (*http.Response, error) {
+	if len(offerIds) == 0 {
+		return queued(), nil
+	}
+
+	key := ""
+	if filters != nil {
+		key = proto.CompactTextString(filters)
+	}
+
+	b.lock.Lock()
+	if b.closed {
+		b.lock.Unlock()
+		return b.Scheduler.DeclineContext(ctx, offerIds, filters)
+	}
+
+	batch, ok := b.declines[key]
+	if !ok {
+		batch = &declineBatch{
+			filters: filters,
+			seen:    make(map[string]bool),
+		}
+		b.declines[key] = batch
+		b.pending.Add(1)
+		batch.timer = time.AfterFunc(b.config.DeclineWindow, func() {
+			b.flushDecline(key, batch)
+		})
+	}
+
+	for _, id := range offerIds {
+		if !batch.seen[id.GetValue()] {
+			batch.seen[id.GetValue()] = true
+			batch.offerIds = append(batch.offerIds, id)
+		}
+	}
+	full := len(batch.offerIds) >= b.config.MaxDeclines
+	b.lock.Unlock()
+
+	if full {
+		b.flushDecline(key, batch)
+	}
+
+	return queued(), nil
+}
+
+// Sends a batch of declines unless it's already been sent by someone else.
+func (b *BatchingScheduler) flushDecline(key string, batch *declineBatch) {
+	b.lock.Lock()
+	if b.declines[key] != batch {
+		b.lock.Unlock()
+		return
+	}
+	delete(b.declines, key)
+	b.lock.Unlock()
+
+	defer b.pending.Done()
+	batch.timer.Stop()
+
+	resp, err := b.Scheduler.DeclineContext(context.Background(), batch.offerIds, batch.filters)
+	if err != nil {
+		b.logger.Emit(logging.ERROR, "Failed to decline %d offers: %s", len(batch.offerIds), err.Error())
+	}
+	closeBody(resp)
+}
+
+// Queues a status update to be acknowledged, blocking if too many are already waiting.
+func (b *BatchingScheduler) Acknowledge(agentId *mesos_v1.AgentID, taskId *mesos_v1.TaskID, uuid []byte) (*http.Response, error) {
+	return b.AcknowledgeContext(context.Background(), agentId, taskId, uuid)
+}
+
+// The context bounds how long we're willing to wait for room in the queue.
+func (b *BatchingScheduler) AcknowledgeContext(ctx context.Context, agentId *mesos_v1.AgentID, taskId *mesos_v1.TaskID, uuid []byte) (*http.Response, error) {
+	b.ackLock.RLock()
+	defer b.ackLock.RUnlock()
+
+	if b.ackClosed {
+		return b.Scheduler.AcknowledgeContext(ctx, agentId, taskId, uuid)
+	}
+
+	select {
+	case b.acks <- acknowledgement{agentId: agentId, taskId: taskId, uuid: uuid}:
+		return queued(), nil
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+// Sends queued acknowledgements until the queue is closed and drained.
+func (b *BatchingScheduler) acknowledge() {
+	defer b.workers.Done()
+
+	for ack := range b.acks {
+		resp, err := b.Scheduler.AcknowledgeContext(context.Background(), ack.agentId, ack.taskId, ack.uuid)
+		if err != nil {
+			b.logger.Emit(logging.ERROR, "Failed to acknowledge update for task %s: %s", ack.taskId.GetValue(), err.Error())
+		}
+		closeBody(resp)
+	}
+}
+
+// Sends everything that's still pending and waits for it to finish.
+// Any calls made afterwards go straight to the wrapped scheduler.
+func (b *BatchingScheduler) Close() {
+	b.lock.Lock()
+	b.closed = true
+	batches := make(map[string]*declineBatch, len(b.declines))
+	for key, batch := range b.declines {
+		batches[key] = batch
+	}
+	b.lock.Unlock()
+
+	for key, batch := range batches {
+		b.flushDecline(key, batch)
+	}
+
+	b.ackLock.Lock()
+	if !b.ackClosed {
+		b.ackClosed = true
+		close(b.acks)
+	}
+	b.ackLock.Unlock()
+
+	b.workers.Wait()
+	b.pending.Wait()
+}
+
+// Stands in for the response to a call that's been queued but not sent yet.
+func queued() *http.Response {
+	return &http.Response{
+		Status:     "202 Accepted",
+		StatusCode: http.StatusAccepted,
+		Proto:      "HTTP/1.1",
+		ProtoMajor: 1,
+		ProtoMinor: 1,
+		Header:     make(http.Header),
+		Body:       ioutil.NopCloser(bytes.NewReader(nil)),
+	}
+}
+
+// Nobody reads the responses of batched calls so we make sure their connections are released.
+func closeBody(resp *http.Response) {
+	if resp != nil && resp.Body != nil {
+		resp.Body.Close()
+	}
+}
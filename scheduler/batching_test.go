@@ -0,0 +1,212 @@
+// Copyright 2017 Verizon
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package scheduler
+
+import (
+	"context"
+	"github.com/golang/protobuf/proto"
+	"github.com/verizonlabs/mesos-framework-sdk/include/mesos_v1"
+	"github.com/verizonlabs/mesos-framework-sdk/include/mesos_v1_scheduler"
+	"net/http"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+)
+
+// Keeps track of every call that reaches Mesos.
+// Tests that need the master to react to calls, or to fail them, can hook into each call once it's been recorded.
+type recordingClient struct {
+	mockClient
+	calls  []*mesos_v1_scheduler.Call
+	onCall func(*mesos_v1_scheduler.Call) error
+	lock   sync.Mutex
+}
+
+func (r *recordingClient) RequestContext(ctx context.Context, call interface{}) (*http.Response, error) {
+	r.lock.Lock()
+	r.calls = append(r.calls, call.(*mesos_v1_scheduler.Call))
+	r.lock.Unlock()
+
+	if r.onCall != nil {
+		if err := r.onCall(call.(*mesos_v1_scheduler.Call)); err != nil {
+			return nil, err
+		}
+	}
+
+	return new(http.Response), nil
+}
+
+func (r *recordingClient) byType(t mesos_v1_scheduler.Call_Type) []*mesos_v1_scheduler.Call {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	var calls []*mesos_v1_scheduler.Call
+	for _, c := range r.calls {
+		if c.GetType() == t {
+			calls = append(calls, c)
+		}
+	}
+
+	return calls
+}
+
+func offerIds(prefix string, n int) []*mesos_v1.OfferID {
+	ids := make([]*mesos_v1.OfferID, n)
+	for i := range ids {
+		ids[i] = &mesos_v1.OfferID{Value: proto.String(prefix + strconv.Itoa(i))}
+	}
+
+	return ids
+}
+
+// Makes sure declines with the same filters are merged into a single call.
+func TestBatchingScheduler_Decline(t *testing.T) {
+	t.Parallel()
+
+	r := new(recordingClient)
+	b := NewBatchingScheduler(NewDefaultScheduler(r, i, l), BatchingConfig{DeclineWindow: time.Hour}, l)
+
+	filters := &mesos_v1.Filters{RefuseSeconds: proto.Float64(5)}
+	for _, id := range offerIds("offer", 10) {
+		b.Decline([]*mesos_v1.OfferID{id}, filters)
+	}
+	b.Decline(offerIds("offer", 1), filters)
+	b.Decline(offerIds("other", 1), nil)
+
+	if len(r.byType(mesos_v1_scheduler.Call_DECLINE)) != 0 {
+		t.Fatal("Declines should have been held until the window closed")
+	}
+
+	b.Close()
+
+	declines := r.byType(mesos_v1_scheduler.Call_DECLINE)
+	if len(declines) != 2 {
+		t.Fatalf("Expected 2 declines, got %d", len(declines))
+	}
+
+	for _, d := range declines {
+		if d.Decline.Filters == filters && len(d.Decline.OfferIds) != 10 {
+			t.Fatalf("Expected 10 unique offers in one decline, got %d", len(d.Decline.OfferIds))
+		}
+	}
+}
+
+// Ensures batches are sent once the window closes or they fill up.
+func TestBatchingScheduler_DeclineFlush(t *testing.T) {
+	t.Parallel()
+
+	r := new(recordingClient)
+	b := NewBatchingScheduler(NewDefaultScheduler(r, i, l), BatchingConfig{
+		DeclineWindow: 10 * time.Millisecond,
+		MaxDeclines:   5,
+	}, l)
+	defer b.Close()
+
+	resp, err := b.Decline(offerIds("full", 5), nil)
+	if err != nil || resp.StatusCode != http.StatusAccepted {
+		t.Fatal("Decline should have a placeholder response")
+	}
+	resp.Body.Close()
+
+	if len(r.byType(mesos_v1_scheduler.Call_DECLINE)) != 1 {
+		t.Fatal("Full batch should have been sent right away")
+	}
+
+	b.Decline(offerIds("window", 1), nil)
+	time.Sleep(100 * time.Millisecond)
+	if len(r.byType(mesos_v1_scheduler.Call_DECLINE)) != 2 {
+		t.Fatal("Batch should have been sent once the window closed")
+	}
+}
+
+// Checks that queued acknowledgements are all sent by the time we close.
+func TestBatchingScheduler_Acknowledge(t *testing.T) {
+	t.Parallel()
+
+	r := new(recordingClient)
+	b := NewBatchingScheduler(NewDefaultScheduler(r, i, l), BatchingConfig{AckWorkers: 2, AckQueue: 1}, l)
+
+	for n := 0; n < 20; n++ {
+		resp, err := b.Acknowledge(&mesos_v1.AgentID{Value: proto.String("agent")}, &mesos_v1.TaskID{Value: proto.String("task")}, []byte("uuid"))
+		if err != nil {
+			t.Fatal(err.Error())
+		}
+
+		// Callers should be able to handle this like any other response.
+		if resp.StatusCode != http.StatusAccepted {
+			t.Fatal("Queued acknowledgement should have a placeholder response")
+		}
+		resp.Body.Close()
+	}
+
+	b.Close()
+
+	if len(r.byType(mesos_v1_scheduler.Call_ACKNOWLEDGE)) != 20 {
+		t.Fatal("Not all acknowledgements were sent")
+	}
+
+	// Once closed, calls are sent directly.
+	b.Acknowledge(&mesos_v1.AgentID{Value: proto.String("agent")}, &mesos_v1.TaskID{Value: proto.String("task")}, []byte("uuid"))
+	if len(r.byType(mesos_v1_scheduler.Call_ACKNOWLEDGE)) != 21 {
+		t.Fatal("Acknowledgement should have been sent after closing")
+	}
+}
+
+// Makes sure callers give up waiting for room in the queue when their context is done.
+func TestBatchingScheduler_AcknowledgeBackPressure(t *testing.T) {
+	t.Parallel()
+
+	s := &blockingScheduler{release: make(chan struct{})}
+	b := NewBatchingScheduler(s, BatchingConfig{AckWorkers: 1, AckQueue: -1}, l)
+
+	agent := &mesos_v1.AgentID{Value: proto.String("agent")}
+	task := &mesos_v1.TaskID{Value: proto.String("task")}
+	b.Acknowledge(agent, task, nil)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+
+	_, err := b.AcknowledgeContext(ctx, agent, task, nil)
+	if err != context.DeadlineExceeded {
+		t.Fatal("Acknowledgement should have been blocked by the busy worker")
+	}
+
+	close(s.release)
+	b.Close()
+}
+
+// Holds up acknowledgements until released.
+type blockingScheduler struct {
+	Scheduler
+	release chan struct{}
+}
+
+func (s *blockingScheduler) AcknowledgeContext(ctx context.Context, agentId *mesos_v1.AgentID, taskId *mesos_v1.TaskID, uuid []byte) (*http.Response, error) {
+	<-s.release
+	return nil, nil
+}
+
+// Measures performance of queueing declines.
+func BenchmarkBatchingScheduler_Decline(b *testing.B) {
+	s := NewBatchingScheduler(NewDefaultScheduler(new(recordingClient), i, l), BatchingConfig{}, l)
+	ids := offerIds("offer", 1)
+	b.ResetTimer()
+
+	for n := 0; n < b.N; n++ {
+		s.Decline(ids, nil)
+	}
+	s.Close()
+}
@@ -0,0 +1,233 @@
+// Copyright 2017 Verizon
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package scheduler
+
+import (
+	"context"
+	"github.com/verizonlabs/mesos-framework-sdk/logging"
+	"github.com/verizonlabs/mesos-framework-sdk/task/manager"
+	"sort"
+	"sync"
+	"time"
+)
+
+/*
+OfferDemandController suppresses offers while we have nothing to launch and revives them as soon as we do.
+
+Any task in the task manager that's STAGING or UNKNOWN counts as work waiting for an offer.
+By default the whole framework is suppressed and revived at once. If roles are given in the config, each one is
+handled on its own based on the tasks that need resources from it.
+Mesos resets suppression whenever we subscribe, so Subscribed needs to be called on every SUBSCRIBED event.
+*/
+type OfferDemandController struct {
+	scheduler  Scheduler
+	tasks      manager.TaskManager
+	config     DemandConfig
+	logger     logging.Logger
+	suppressed map[string]bool // Keyed by role, the whole framework is tracked under an empty role.
+	trigger    chan struct{}
+	lock       sync.Mutex
+}
+
+// Pending work is checked for every 5 seconds unless an interval is given.
+// Without roles the whole framework is suppressed and revived at once.
+type DemandConfig struct {
+	Interval time.Duration // How often Run checks for pending work.
+	Roles    []string      // Roles suppressed and revived on their own, leave empty to handle the whole framework at once.
+
+	// Tells us which role a task needs offers from.
+	// Defaults to the role its resources are allocated to or reserved for, falling back to the framework's role.
+	// Tasks with a role we aren't tracking count as work for every role.
+	RoleOf func(*manager.Task) string
+}
+
+func NewOfferDemandController(s Scheduler, tasks manager.TaskManager, config DemandConfig, logger logging.Logger) *OfferDemandController {
+	if config.Interval <= 0 {
+		config.Interval = 5 * time.Second
+	}
+
+	d := &OfferDemandController{
+		scheduler:  s,
+		tasks:      tasks,
+		config:     config,
+		logger:     logger,
+		suppressed: make(map[string]bool),
+		trigger:    make(chan struct{}, 1),
+	}
+	if d.config.RoleOf == nil {
+		d.config.RoleOf = d.roleOf
+	}
+
+	return d
+}
+
+// Checks for pending work right away and then periodically until the context is done.
+func (d *OfferDemandController) Run(ctx context.Context) error {
+	ticker := time.NewTicker(d.config.Interval)
+	defer ticker.Stop()
+
+	for {
+		d.Update(ctx)
+
+		select {
+		case <-ticker.C:
+		case <-d.trigger:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
+
+// Asks Run to check for pending work as soon as possible, such as after queuing up new tasks.
+func (d *OfferDemandController) Trigger() {
+	select {
+	case d.trigger <- struct{}{}:
+	default:
+	}
+}
+
+// Forgets what we suppressed since Mesos sends offers for every role again after we subscribe.
+func (d *OfferDemandController) Subscribed() {
+	d.lock.Lock()
+	d.suppressed = make(map[string]bool)
+	d.lock.Unlock()
+
+	d.Trigger()
+}
+
+// Tells us if offers are currently suppressed for a role, use an empty role when handling the whole framework.
+func (d *OfferDemandController) Suppressed(role string) bool {
+	d.lock.Lock()
+	defer d.lock.Unlock()
+
+	return d.suppressed[role]
+}
+
+// Suppresses or revives offers based on the work we currently have pending.
+func (d *OfferDemandController) Update(ctx context.Context) error {
+	tasks, err := d.tasks.All()
+	if err != nil {
+		d.logger.Emit(logging.ERROR, "Failed to get tasks for offer demand: %s", err.Error())
+		return err
+	}
+
+	demand := d.demand(tasks)
+
+	d.lock.Lock()
+	var suppress, revive []string
+	for role, wanted := range demand {
+		if wanted && d.suppressed[role] {
+			revive = append(revive, role)
+		} else if !wanted && !d.suppressed[role] {
+			suppress = append(suppress, role)
+		}
+	}
+	d.lock.Unlock()
+
+	if len(revive) > 0 {
+		sort.Strings(revive)
+		resp, err := d.scheduler.ReviveRolesContext(ctx, d.callRoles(revive))
+		closeBody(resp)
+		if err != nil {
+			return err
+		}
+		d.set(revive, false)
+	}
+
+	if len(suppress) > 0 {
+		sort.Strings(suppress)
+		resp, err := d.scheduler.SuppressRolesContext(ctx, d.callRoles(suppress))
+		closeBody(resp)
+		if err != nil {
+			return err
+		}
+		d.set(suppress, true)
+	}
+
+	return nil
+}
+
+// Works out which of our roles have tasks waiting for offers.
+func (d *OfferDemandController) demand(tasks []*manager.Task) map[string]bool {
+	demand := make(map[string]bool)
+	if len(d.config.Roles) == 0 {
+		demand[""] = false
+	}
+	for _, role := range d.config.Roles {
+		demand[role] = false
+	}
+
+	for _, t := range tasks {
+		if t.State != manager.STAGING && t.State != manager.UNKNOWN {
+			continue
+		}
+
+		if len(d.config.Roles) == 0 {
+			demand[""] = true
+			continue
+		}
+
+		role := d.config.RoleOf(t)
+		if _, ok := demand[role]; ok {
+			demand[role] = true
+			continue
+		}
+
+		for role := range demand {
+			demand[role] = true
+		}
+	}
+
+	return demand
+}
+
+// Translates tracked roles into the roles sent to Mesos, where none means all of them.
+func (d *OfferDemandController) callRoles(roles []string) []string {
+	if len(d.config.Roles) == 0 {
+		return nil
+	}
+
+	return roles
+}
+
+func (d *OfferDemandController) set(roles []string, suppressed bool) {
+	d.lock.Lock()
+	defer d.lock.Unlock()
+
+	for _, role := range roles {
+		d.suppressed[role] = suppressed
+	}
+}
+
+// Default way of figuring out which role a task needs offers from.
+// The role the task asked for wins, otherwise we go by the roles on its resources.
+func (d *OfferDemandController) roleOf(t *manager.Task) string {
+	if t.Role != "" {
+		return t.Role
+	}
+
+	if t.Info != nil {
+		for _, r := range t.Info.Resources {
+			if role := r.GetAllocationInfo().GetRole(); role != "" {
+				return role
+			}
+			if r.GetRole() != "*" {
+				return r.GetRole()
+			}
+		}
+	}
+
+	return d.scheduler.FrameworkInfo().GetRole()
+}
@@ -0,0 +1,172 @@
+// Copyright 2017 Verizon
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package scheduler
+
+import (
+	"context"
+	"github.com/golang/protobuf/proto"
+	"github.com/verizonlabs/mesos-framework-sdk/include/mesos_v1"
+	"github.com/verizonlabs/mesos-framework-sdk/include/mesos_v1_scheduler"
+	"github.com/verizonlabs/mesos-framework-sdk/task/manager"
+	"testing"
+)
+
+func roleTask(id, role string, state mesos_v1.TaskState) *manager.Task {
+	t := mockTask(id, "agent", state)
+	t.Info.Resources = []*mesos_v1.Resource{
+		{
+			Name:   proto.String("cpus"),
+			Type:   mesos_v1.Value_SCALAR.Enum(),
+			Scalar: &mesos_v1.Value_Scalar{Value: proto.Float64(1)},
+			Role:   proto.String(role),
+		},
+	}
+
+	return t
+}
+
+// Makes sure the whole framework is suppressed without work and revived once some shows up.
+func TestOfferDemandController_Update(t *testing.T) {
+	t.Parallel()
+
+	r := new(recordingClient)
+	tasks := &mockTaskManager{
+		tasks: []*manager.Task{mockTask("running", "agent", manager.RUNNING)},
+	}
+	d := NewOfferDemandController(NewDefaultScheduler(r, i, l), tasks, DemandConfig{}, l)
+
+	d.Update(context.Background())
+	d.Update(context.Background())
+	suppressed := r.byType(mesos_v1_scheduler.Call_SUPPRESS)
+	if len(suppressed) != 1 || len(suppressed[0].Suppress.Roles) != 0 || !d.Suppressed("") {
+		t.Fatal("Offers should have been suppressed once for the whole framework")
+	}
+
+	tasks.tasks = append(tasks.tasks, mockTask("pending", "", manager.STAGING))
+	d.Update(context.Background())
+	if len(r.byType(mesos_v1_scheduler.Call_REVIVE)) != 1 || d.Suppressed("") {
+		t.Fatal("Offers should have been revived for the pending task")
+	}
+
+	// Mesos unsuppresses us on resubscription, so we need to suppress again.
+	tasks.tasks = tasks.tasks[:1]
+	d.Update(context.Background())
+	d.Subscribed()
+	d.Update(context.Background())
+	if len(r.byType(mesos_v1_scheduler.Call_SUPPRESS)) != 3 {
+		t.Fatal("Offers should have been suppressed again after subscribing")
+	}
+}
+
+// Ensures roles are suppressed and revived on their own.
+func TestOfferDemandController_Roles(t *testing.T) {
+	t.Parallel()
+
+	r := new(recordingClient)
+	tasks := &mockTaskManager{
+		tasks: []*manager.Task{
+			roleTask("web", "web", manager.STAGING),
+			roleTask("db", "db", manager.RUNNING),
+		},
+	}
+	d := NewOfferDemandController(NewDefaultScheduler(r, i, l), tasks, DemandConfig{Roles: []string{"web", "db"}}, l)
+
+	d.Update(context.Background())
+	suppressed := r.byType(mesos_v1_scheduler.Call_SUPPRESS)
+	if len(suppressed) != 1 || len(suppressed[0].Suppress.Roles) != 1 || suppressed[0].Suppress.Roles[0] != "db" {
+		t.Fatal("Only the role without pending work should have been suppressed")
+	}
+
+	tasks.tasks = append(tasks.tasks, roleTask("other", "*", manager.UNKNOWN))
+	d.Update(context.Background())
+	revived := r.byType(mesos_v1_scheduler.Call_REVIVE)
+	if len(revived) != 1 || len(revived[0].Revive.Roles) != 1 || revived[0].Revive.Roles[0] != "db" {
+		t.Fatal("Tasks without a tracked role should revive every role")
+	}
+
+	if d.Suppressed("db") || d.Suppressed("web") {
+		t.Fatal("No role should be suppressed")
+	}
+}
+
+// Makes sure the role a task asked for is used over the roles on its resources.
+func TestOfferDemandController_TaskRole(t *testing.T) {
+	t.Parallel()
+
+	r := new(recordingClient)
+	pending := roleTask("web", "*", manager.STAGING)
+	pending.Role = "web"
+	tasks := &mockTaskManager{
+		tasks: []*manager.Task{pending},
+	}
+	d := NewOfferDemandController(NewDefaultScheduler(r, i, l), tasks, DemandConfig{Roles: []string{"web", "db"}}, l)
+
+	d.Update(context.Background())
+	suppressed := r.byType(mesos_v1_scheduler.Call_SUPPRESS)
+	if len(suppressed) != 1 || len(suppressed[0].Suppress.Roles) != 1 || suppressed[0].Suppress.Roles[0] != "db" {
+		t.Fatal("Only offers for the role the task asked for should be wanted")
+	}
+	if d.Suppressed("web") {
+		t.Fatal("The role the task asked for should not be suppressed")
+	}
+}
+
+// Tests our per role suppress and revive calls.
+func TestDefaultScheduler_SuppressRoles(t *testing.T) {
+	t.Parallel()
+
+	r := new(recordingClient)
+	s := NewDefaultScheduler(r, i, l)
+
+	s.SuppressRoles([]string{"role"})
+	if s.IsSuppressed {
+		t.Fatal("Suppressing a single role should not mark the framework as suppressed")
+	}
+
+	s.SuppressRoles(nil)
+	if !s.IsSuppressed {
+		t.Fatal("Suppressing every role should mark the framework as suppressed")
+	}
+
+	s.ReviveRoles(nil)
+	if s.IsSuppressed || len(r.byType(mesos_v1_scheduler.Call_REVIVE)) != 1 {
+		t.Fatal("Reviving every role should unmark the framework as suppressed")
+	}
+}
+
+// Makes sure a full revive undoes roles that were suppressed on their own.
+func TestDefaultScheduler_ReviveSuppressedRoles(t *testing.T) {
+	t.Parallel()
+
+	r := new(recordingClient)
+	s := NewDefaultScheduler(r, i, l)
+
+	s.Revive()
+	if len(r.byType(mesos_v1_scheduler.Call_REVIVE)) != 0 {
+		t.Fatal("Nothing should be revived when nothing was suppressed")
+	}
+
+	s.SuppressRoles([]string{"web", "db"})
+	s.ReviveRoles([]string{"web"})
+	s.Revive()
+	if len(r.byType(mesos_v1_scheduler.Call_REVIVE)) != 2 {
+		t.Fatal("Revive should have been sent while a role was still suppressed")
+	}
+
+	s.Revive()
+	if len(r.byType(mesos_v1_scheduler.Call_REVIVE)) != 2 {
+		t.Fatal("Nothing should be revived once every role has been revived")
+	}
+}
@@ -0,0 +1,227 @@
+// Copyright 2017 Verizon
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package events
+
+import (
+	"context"
+	"github.com/verizonlabs/mesos-framework-sdk/include/mesos_v1_scheduler"
+	"github.com/verizonlabs/mesos-framework-sdk/logging"
+	resourceManager "github.com/verizonlabs/mesos-framework-sdk/resources/manager"
+	"github.com/verizonlabs/mesos-framework-sdk/task/manager"
+	"hash/fnv"
+	"runtime/debug"
+	"sync"
+)
+
+/*
+Dispatcher reads events from the subscription and routes each one to the handler registered for its type.
+
+HEARTBEAT, RESCIND and ERROR events are logged by default, everything else is dropped until a handler is registered.
+Logging a rescinded offer doesn't stop it from being used, so register HandleRescinds or a handler of your own
+that calls ResourceManager.Rescind for that.
+Handlers run on a pool of workers. Status updates are assigned to workers by task, so updates for the same task
+are always handled in the order Mesos sent them, while messages and failures are assigned by agent.
+Events that aren't tied to a task or agent, such as offers, all go to the same worker and stay in order as well.
+Nothing is ordered across workers, so an update can be handled before an earlier failure of its agent.
+A panicking handler is logged and doesn't take the dispatcher down with it.
+*/
+type Dispatcher struct {
+	handlers map[mesos_v1_scheduler.Event_Type]Handler
+	config   DispatcherConfig
+	logger   logging.Logger
+	lock     sync.RWMutex
+}
+
+// Handles a single event received from Mesos.
+type Handler func(*mesos_v1_scheduler.Event)
+
+// Without any workers configured everything is handled in order on one goroutine, with up to 100 events buffered.
+type DispatcherConfig struct {
+	Workers   int // Number of handlers allowed to run at once, defaults to handling everything in order.
+	QueueSize int // Events buffered per worker before we stop reading from the subscription.
+}
+
+func NewDispatcher(config DispatcherConfig, logger logging.Logger) *Dispatcher {
+	if config.Workers <= 0 {
+		config.Workers = 1
+	}
+	if config.QueueSize <= 0 {
+		config.QueueSize = 100
+	}
+
+	d := &Dispatcher{
+		handlers: make(map[mesos_v1_scheduler.Event_Type]Handler),
+		config:   config,
+		logger:   logger,
+	}
+
+	d.handlers[mesos_v1_scheduler.Event_HEARTBEAT] = func(*mesos_v1_scheduler.Event) {
+		logger.Emit(logging.DEBUG, "Received heartbeat")
+	}
+	d.handlers[mesos_v1_scheduler.Event_RESCIND] = func(e *mesos_v1_scheduler.Event) {
+		logger.Emit(logging.INFO, "Offer %s was rescinded", e.GetRescind().GetOfferId().GetValue())
+	}
+	d.handlers[mesos_v1_scheduler.Event_ERROR] = func(e *mesos_v1_scheduler.Event) {
+		logger.Emit(logging.ERROR, "Received error from Mesos: %s", e.GetError().GetMessage())
+	}
+
+	return d
+}
+
+// Registers the handler for an event type, replacing any existing one.
+// Passing a nil handler drops events of that type.
+func (d *Dispatcher) Handle(t mesos_v1_scheduler.Event_Type, h Handler) {
+	d.lock.Lock()
+	defer d.lock.Unlock()
+
+	if h == nil {
+		delete(d.handlers, t)
+		return
+	}
+	d.handlers[t] = h
+}
+
+// Registers the event callbacks of an existing SchedulerEvent implementation.
+func (d *Dispatcher) HandleSchedulerEvent(e SchedulerEvent) {
+	d.Handle(mesos_v1_scheduler.Event_SUBSCRIBED, func(event *mesos_v1_scheduler.Event) {
+		e.Subscribed(event.GetSubscribed())
+	})
+	d.Handle(mesos_v1_scheduler.Event_OFFERS, func(event *mesos_v1_scheduler.Event) {
+		e.Offers(event.GetOffers())
+	})
+	d.Handle(mesos_v1_scheduler.Event_RESCIND, func(event *mesos_v1_scheduler.Event) {
+		e.Rescind(event.GetRescind())
+	})
+	d.Handle(mesos_v1_scheduler.Event_UPDATE, func(event *mesos_v1_scheduler.Event) {
+		e.Update(event.GetUpdate())
+	})
+	d.Handle(mesos_v1_scheduler.Event_MESSAGE, func(event *mesos_v1_scheduler.Event) {
+		e.Message(event.GetMessage())
+	})
+	d.Handle(mesos_v1_scheduler.Event_FAILURE, func(event *mesos_v1_scheduler.Event) {
+		e.Failure(event.GetFailure())
+	})
+	d.Handle(mesos_v1_scheduler.Event_ERROR, func(event *mesos_v1_scheduler.Event) {
+		e.Error(event.GetError())
+	})
+	d.Handle(mesos_v1_scheduler.Event_INVERSE_OFFERS, func(event *mesos_v1_scheduler.Event) {
+		e.InverseOffer(event.GetInverseOffers())
+	})
+	d.Handle(mesos_v1_scheduler.Event_RESCIND_INVERSE_OFFER, func(event *mesos_v1_scheduler.Event) {
+		e.RescindInverseOffer(event.GetRescindInverseOffer())
+	})
+}
+
+// Takes rescinded offers away from the resource manager and updates the tasks that were assigned to them,
+// which were put back in STAGING so that they're launched on the next round of offers.
+func (d *Dispatcher) HandleRescinds(offers resourceManager.ResourceManager, tasks manager.TaskManager) {
+	d.Handle(mesos_v1_scheduler.Event_RESCIND, func(e *mesos_v1_scheduler.Event) {
+		id := e.GetRescind().GetOfferId()
+		d.logger.Emit(logging.INFO, "Offer %s was rescinded", id.GetValue())
+
+		rescinded := offers.Rescind(id)
+		if len(rescinded) == 0 {
+			return
+		}
+
+		if err := tasks.Update(rescinded...); err != nil {
+			d.logger.Emit(logging.ERROR, "Failed to update tasks assigned to rescinded offer %s: %s", id.GetValue(), err.Error())
+		}
+	})
+}
+
+// Dispatches events until the channel is closed or the context is done.
+// Events that were already read are handled before returning.
+func (d *Dispatcher) Run(ctx context.Context, events <-chan *mesos_v1_scheduler.Event) error {
+	queues := make([]chan *mesos_v1_scheduler.Event, d.config.Workers)
+	var wg sync.WaitGroup
+	for i := range queues {
+		queues[i] = make(chan *mesos_v1_scheduler.Event, d.config.QueueSize)
+		wg.Add(1)
+		go func(queue chan *mesos_v1_scheduler.Event) {
+			defer wg.Done()
+			for event := range queue {
+				d.Dispatch(event)
+			}
+		}(queues[i])
+	}
+
+	defer func() {
+		for _, queue := range queues {
+			close(queue)
+		}
+		wg.Wait()
+	}()
+
+	for {
+		select {
+		case event, ok := <-events:
+			if !ok {
+				return nil
+			}
+
+			select {
+			case queues[d.worker(event)] <- event:
+			case <-ctx.Done():
+				return ctx.Err()
+			}
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
+
+// Hands a single event to its handler right away.
+func (d *Dispatcher) Dispatch(event *mesos_v1_scheduler.Event) {
+	d.lock.RLock()
+	h, ok := d.handlers[event.GetType()]
+	d.lock.RUnlock()
+
+	if !ok {
+		d.logger.Emit(logging.DEBUG, "No handler registered for %s event", event.GetType().String())
+		return
+	}
+
+	defer func() {
+		if r := recover(); r != nil {
+			d.logger.Emit(logging.ERROR, "Handler for %s event panicked: %v\n%s", event.GetType().String(), r, debug.Stack())
+		}
+	}()
+
+	h(event)
+}
+
+// Picks the worker for an event so that everything about the same task or agent lands in the same place.
+// Updates are keyed by task alone since the agent ID isn't always set on them.
+func (d *Dispatcher) worker(event *mesos_v1_scheduler.Event) int {
+	if d.config.Workers == 1 {
+		return 0
+	}
+
+	var key string
+	switch event.GetType() {
+	case mesos_v1_scheduler.Event_UPDATE:
+		key = event.GetUpdate().GetStatus().GetTaskId().GetValue()
+	case mesos_v1_scheduler.Event_MESSAGE:
+		key = event.GetMessage().GetAgentId().GetValue()
+	case mesos_v1_scheduler.Event_FAILURE:
+		key = event.GetFailure().GetAgentId().GetValue()
+	}
+
+	h := fnv.New32a()
+	h.Write([]byte(key))
+
+	return int(h.Sum32() % uint32(d.config.Workers))
+}
@@ -0,0 +1,284 @@
+// Copyright 2017 Verizon
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package events
+
+import (
+	"context"
+	"fmt"
+	"github.com/golang/protobuf/proto"
+	"github.com/verizonlabs/mesos-framework-sdk/include/mesos_v1"
+	"github.com/verizonlabs/mesos-framework-sdk/include/mesos_v1_scheduler"
+	"github.com/verizonlabs/mesos-framework-sdk/logging"
+	"github.com/verizonlabs/mesos-framework-sdk/resources"
+	resourceManager "github.com/verizonlabs/mesos-framework-sdk/resources/manager"
+	"github.com/verizonlabs/mesos-framework-sdk/task/manager"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+)
+
+// Keeps everything that was logged so we can check what the default handlers do.
+type recordingLogger struct {
+	lock    sync.Mutex
+	entries map[uint8][]string
+}
+
+func (r *recordingLogger) Emit(severity uint8, template string, args ...interface{}) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	if r.entries == nil {
+		r.entries = make(map[uint8][]string)
+	}
+	r.entries[severity] = append(r.entries[severity], fmt.Sprintf(template, args...))
+}
+
+func (r *recordingLogger) logged(severity uint8) []string {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	return r.entries[severity]
+}
+
+func update(task, agent string, seq int) *mesos_v1_scheduler.Event {
+	status := &mesos_v1.TaskStatus{
+		TaskId:  &mesos_v1.TaskID{Value: proto.String(task)},
+		State:   mesos_v1.TaskState_TASK_RUNNING.Enum(),
+		Message: proto.String(strconv.Itoa(seq)),
+	}
+	if agent != "" {
+		status.AgentId = &mesos_v1.AgentID{Value: proto.String(agent)}
+	}
+
+	return &mesos_v1_scheduler.Event{
+		Type:   mesos_v1_scheduler.Event_UPDATE.Enum(),
+		Update: &mesos_v1_scheduler.Event_Update{Status: status},
+	}
+}
+
+// Makes sure events only reach the handler registered for their type.
+func TestDispatcher_Routing(t *testing.T) {
+	t.Parallel()
+
+	logger := new(recordingLogger)
+	d := NewDispatcher(DispatcherConfig{}, logger)
+
+	var offers, updates int
+	d.Handle(mesos_v1_scheduler.Event_OFFERS, func(*mesos_v1_scheduler.Event) { offers++ })
+	d.Handle(mesos_v1_scheduler.Event_UPDATE, func(*mesos_v1_scheduler.Event) { updates++ })
+
+	d.Dispatch(&mesos_v1_scheduler.Event{Type: mesos_v1_scheduler.Event_OFFERS.Enum()})
+	d.Dispatch(update("task", "agent", 0))
+	d.Dispatch(update("task", "agent", 1))
+	if offers != 1 || updates != 2 {
+		t.Fatal("Events were not routed to the handler for their type")
+	}
+
+	d.Dispatch(&mesos_v1_scheduler.Event{Type: mesos_v1_scheduler.Event_MESSAGE.Enum()})
+	if len(logger.logged(logging.DEBUG)) != 1 {
+		t.Fatal("Events without a handler should be dropped and logged")
+	}
+
+	d.Handle(mesos_v1_scheduler.Event_OFFERS, nil)
+	d.Dispatch(&mesos_v1_scheduler.Event{Type: mesos_v1_scheduler.Event_OFFERS.Enum()})
+	if offers != 1 {
+		t.Fatal("Removing a handler should drop events of its type")
+	}
+}
+
+// Checks what's logged for the events we handle out of the box.
+func TestDispatcher_DefaultHandlers(t *testing.T) {
+	t.Parallel()
+
+	logger := new(recordingLogger)
+	d := NewDispatcher(DispatcherConfig{}, logger)
+
+	d.Dispatch(&mesos_v1_scheduler.Event{Type: mesos_v1_scheduler.Event_HEARTBEAT.Enum()})
+	if logged := logger.logged(logging.DEBUG); len(logged) != 1 || logged[0] != "Received heartbeat" {
+		t.Fatal("Heartbeats should be logged")
+	}
+
+	d.Dispatch(&mesos_v1_scheduler.Event{
+		Type: mesos_v1_scheduler.Event_RESCIND.Enum(),
+		Rescind: &mesos_v1_scheduler.Event_Rescind{
+			OfferId: &mesos_v1.OfferID{Value: proto.String("offer")},
+		},
+	})
+	if logged := logger.logged(logging.INFO); len(logged) != 1 || logged[0] != "Offer offer was rescinded" {
+		t.Fatal("Rescinded offers should be logged")
+	}
+
+	d.Dispatch(&mesos_v1_scheduler.Event{
+		Type:  mesos_v1_scheduler.Event_ERROR.Enum(),
+		Error: &mesos_v1_scheduler.Event_Error{Message: proto.String("boom")},
+	})
+	if logged := logger.logged(logging.ERROR); len(logged) != 1 || logged[0] != "Received error from Mesos: boom" {
+		t.Fatal("Errors from Mesos should be logged")
+	}
+}
+
+// Makes sure a panicking handler is logged and doesn't stop later events from being handled.
+func TestDispatcher_Panic(t *testing.T) {
+	t.Parallel()
+
+	logger := new(recordingLogger)
+	d := NewDispatcher(DispatcherConfig{Workers: 2}, logger)
+
+	var handled int
+	d.Handle(mesos_v1_scheduler.Event_UPDATE, func(e *mesos_v1_scheduler.Event) {
+		if e.GetUpdate().GetStatus().GetMessage() == "0" {
+			panic("bad update")
+		}
+		handled++
+	})
+
+	events := make(chan *mesos_v1_scheduler.Event, 2)
+	events <- update("task", "agent", 0)
+	events <- update("task", "agent", 1)
+	close(events)
+
+	if err := d.Run(context.Background(), events); err != nil {
+		t.Fatal(err.Error())
+	}
+
+	if handled != 1 {
+		t.Fatal("Events after a panic should still be handled")
+	}
+	if len(logger.logged(logging.ERROR)) != 1 {
+		t.Fatal("The panic should have been logged")
+	}
+}
+
+// Checks that updates for the same task are handled in order with several workers,
+// even when only some of them carry an agent ID.
+func TestDispatcher_Ordering(t *testing.T) {
+	t.Parallel()
+
+	d := NewDispatcher(DispatcherConfig{Workers: 4}, new(recordingLogger))
+
+	var lock sync.Mutex
+	seen := make(map[string][]int)
+	d.Handle(mesos_v1_scheduler.Event_UPDATE, func(e *mesos_v1_scheduler.Event) {
+		status := e.GetUpdate().GetStatus()
+		seq, _ := strconv.Atoi(status.GetMessage())
+
+		// Slow down updates without an agent so that any later ones sent to another worker would overtake them.
+		if status.AgentId == nil {
+			time.Sleep(time.Millisecond)
+		}
+
+		lock.Lock()
+		defer lock.Unlock()
+		seen[status.GetTaskId().GetValue()] = append(seen[status.GetTaskId().GetValue()], seq)
+	})
+
+	const tasks, updates = 8, 20
+	events := make(chan *mesos_v1_scheduler.Event)
+	go func() {
+		for seq := 0; seq < updates; seq++ {
+			for i := 0; i < tasks; i++ {
+				agent := "agent" + strconv.Itoa(i%3)
+				if seq%2 == 0 {
+					agent = ""
+				}
+				events <- update("task"+strconv.Itoa(i), agent, seq)
+			}
+		}
+		close(events)
+	}()
+
+	if err := d.Run(context.Background(), events); err != nil {
+		t.Fatal(err.Error())
+	}
+
+	if len(seen) != tasks {
+		t.Fatal("Updates were lost")
+	}
+	for task, order := range seen {
+		if len(order) != updates {
+			t.Fatal("Updates for " + task + " were lost")
+		}
+		for i, seq := range order {
+			if seq != i {
+				t.Fatal("Updates for", task, "were handled out of order:", order)
+			}
+		}
+	}
+}
+
+// Makes sure we stop reading events once the context is done.
+func TestDispatcher_RunCancelled(t *testing.T) {
+	t.Parallel()
+
+	d := NewDispatcher(DispatcherConfig{}, new(recordingLogger))
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	if err := d.Run(ctx, make(chan *mesos_v1_scheduler.Event)); err != context.Canceled {
+		t.Fatal("Run should stop once the context is cancelled")
+	}
+}
+
+// Keeps track of the tasks that were updated.
+type updatingTaskManager struct {
+	manager.TaskManager
+	updated []*manager.Task
+}
+
+func (u *updatingTaskManager) Update(tasks ...*manager.Task) error {
+	u.updated = append(u.updated, tasks...)
+	return nil
+}
+
+// Makes sure rescinded offers are taken away from the resource manager and their tasks updated.
+func TestDispatcher_HandleRescinds(t *testing.T) {
+	t.Parallel()
+
+	logger := new(recordingLogger)
+	offers := resourceManager.NewDefaultResourceManagerWithConfig(nil, resourceManager.ResourceManagerConfig{}, logger)
+	offers.AddOffers([]*mesos_v1.Offer{{
+		Id:        &mesos_v1.OfferID{Value: proto.String("offer")},
+		AgentId:   &mesos_v1.AgentID{Value: proto.String("agent")},
+		Resources: []*mesos_v1.Resource{resources.CreateResource("cpus", "*", 1)},
+	}})
+
+	task := &manager.Task{
+		Info: &mesos_v1.TaskInfo{
+			TaskId:    &mesos_v1.TaskID{Value: proto.String("task")},
+			Resources: []*mesos_v1.Resource{resources.CreateResource("cpus", "", 1)},
+		},
+	}
+	if _, err := offers.Assign(task); err != nil {
+		t.Fatal(err.Error())
+	}
+
+	tasks := new(updatingTaskManager)
+	d := NewDispatcher(DispatcherConfig{}, logger)
+	d.HandleRescinds(offers, tasks)
+	d.Dispatch(&mesos_v1_scheduler.Event{
+		Type: mesos_v1_scheduler.Event_RESCIND.Enum(),
+		Rescind: &mesos_v1_scheduler.Event_Rescind{
+			OfferId: &mesos_v1.OfferID{Value: proto.String("offer")},
+		},
+	})
+
+	if len(tasks.updated) != 1 || tasks.updated[0] != task || task.State != manager.STAGING {
+		t.Fatal("The task assigned to the rescinded offer should have been rolled back and updated")
+	}
+	if len(logger.logged(logging.INFO)) != 1 {
+		t.Fatal("The rescinded offer should still be logged")
+	}
+}
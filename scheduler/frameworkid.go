@@ -0,0 +1,149 @@
+// Copyright 2017 Verizon
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package scheduler
+
+import (
+	"encoding/json"
+	"github.com/verizonlabs/mesos-framework-sdk/include/mesos_v1"
+	"github.com/verizonlabs/mesos-framework-sdk/logging"
+	"github.com/verizonlabs/mesos-framework-sdk/persistence"
+	"strings"
+	"sync"
+	"time"
+)
+
+const FRAMEWORK_ID_KEY = "/frameworkId"
+
+/*
+FrameworkIdStore persists the framework ID handed to us by Mesos so that a restarted scheduler picks its tasks back up.
+
+Along with the ID we store the last time we knew we were connected, which is refreshed on heartbeats.
+A stored ID is only reused if we're still within the failover timeout, otherwise Mesos has already torn down
+the framework and we register as a new one.
+*/
+type FrameworkIdStore struct {
+	kv      persistence.KeyValueStore
+	key     string
+	logger  logging.Logger
+	refresh time.Duration // How often heartbeats update the stored timestamp.
+	updated time.Time
+	id      string
+	lock    sync.Mutex
+}
+
+type storedFrameworkId struct {
+	Id      string `json:"id"`
+	Updated int64  `json:"updated"` // Unix timestamp in seconds.
+}
+
+// Creates a store that keeps the framework ID under the given key, or FRAMEWORK_ID_KEY if it's empty.
+func NewFrameworkIdStore(kv persistence.KeyValueStore, key string, logger logging.Logger) *FrameworkIdStore {
+	if key == "" {
+		key = FRAMEWORK_ID_KEY
+	}
+
+	return &FrameworkIdStore{
+		kv:      kv,
+		key:     key,
+		logger:  logger,
+		refresh: time.Minute,
+	}
+}
+
+// Sets the framework ID on the framework info if we have one that's still within the failover timeout.
+// Stale IDs are removed from storage.
+func (f *FrameworkIdStore) Load(info *mesos_v1.FrameworkInfo) error {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+
+	value, err := f.kv.Read(f.key)
+	if err != nil {
+		return err
+	}
+	if value == "" {
+		return nil
+	}
+
+	var stored storedFrameworkId
+	if err := json.Unmarshal([]byte(value), &stored); err != nil {
+		return err
+	}
+
+	timeout := time.Duration(info.GetFailoverTimeout() * float64(time.Second))
+	if time.Since(time.Unix(stored.Updated, 0)) > timeout {
+		f.logger.Emit(logging.INFO, "Stored framework ID %s is past the failover timeout, registering as a new framework", stored.Id)
+		return f.kv.Delete(f.key)
+	}
+
+	f.logger.Emit(logging.INFO, "Reusing framework ID %s", stored.Id)
+	info.Id = &mesos_v1.FrameworkID{Value: &stored.Id}
+	f.id = stored.Id
+	f.updated = time.Unix(stored.Updated, 0)
+
+	return nil
+}
+
+// Stores the framework ID we subscribed with.
+func (f *FrameworkIdStore) Save(id *mesos_v1.FrameworkID) error {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+
+	f.id = id.GetValue()
+
+	return f.store()
+}
+
+// Lets the store know we're still connected, updating the stored timestamp every so often.
+func (f *FrameworkIdStore) Heartbeat() error {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+
+	if f.id == "" || time.Since(f.updated) < f.refresh {
+		return nil
+	}
+
+	return f.store()
+}
+
+// Forgets the stored framework ID.
+func (f *FrameworkIdStore) Clear() error {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+
+	f.id = ""
+	f.updated = time.Time{}
+
+	return f.kv.Delete(f.key)
+}
+
+func (f *FrameworkIdStore) store() error {
+	now := time.Now()
+	value, err := json.Marshal(storedFrameworkId{Id: f.id, Updated: now.Unix()})
+	if err != nil {
+		return err
+	}
+
+	if err := f.kv.Update(f.key, string(value)); err != nil {
+		return err
+	}
+	f.updated = now
+
+	return nil
+}
+
+// Tells us if Mesos removed our framework, in which case its ID can never be used again.
+func IsFrameworkRemoved(message string) bool {
+	return strings.Contains(strings.ToLower(message), "framework has been removed")
+}
@@ -0,0 +1,175 @@
+// Copyright 2017 Verizon
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package scheduler
+
+import (
+	"context"
+	"encoding/json"
+	"github.com/golang/protobuf/proto"
+	"github.com/verizonlabs/mesos-framework-sdk/include/mesos_v1"
+	"github.com/verizonlabs/mesos-framework-sdk/include/mesos_v1_scheduler"
+	"github.com/verizonlabs/mesos-framework-sdk/persistence"
+	"sync"
+	"testing"
+	"time"
+)
+
+// Keeps everything in memory so we can see what was stored.
+type memoryKVStore struct {
+	persistence.KeyValueStore
+	data map[string]string
+	lock sync.Mutex
+}
+
+func (m *memoryKVStore) Read(key string) (string, error) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	return m.data[key], nil
+}
+
+func (m *memoryKVStore) Update(key, value string) error {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	m.data[key] = value
+	return nil
+}
+
+func (m *memoryKVStore) Delete(key string) error {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	delete(m.data, key)
+	return nil
+}
+
+func storedId(t *testing.T, kv *memoryKVStore) string {
+	value, _ := kv.Read(FRAMEWORK_ID_KEY)
+	if value == "" {
+		return ""
+	}
+
+	var stored storedFrameworkId
+	if err := json.Unmarshal([]byte(value), &stored); err != nil {
+		t.Fatal(err.Error())
+	}
+
+	return stored.Id
+}
+
+func storeId(kv *memoryKVStore, id string, updated time.Time) {
+	value, _ := json.Marshal(storedFrameworkId{Id: id, Updated: updated.Unix()})
+	kv.Update(FRAMEWORK_ID_KEY, string(value))
+}
+
+// Makes sure stored IDs are only reused within the failover timeout.
+func TestFrameworkIdStore_Load(t *testing.T) {
+	t.Parallel()
+
+	kv := &memoryKVStore{data: make(map[string]string)}
+	f := NewFrameworkIdStore(kv, "", l)
+	info := &mesos_v1.FrameworkInfo{FailoverTimeout: proto.Float64(60)}
+
+	if err := f.Load(info); err != nil || info.Id != nil {
+		t.Fatal("Nothing should be loaded from an empty store")
+	}
+
+	storeId(kv, "recent", time.Now().Add(-30*time.Second))
+	if err := f.Load(info); err != nil || info.GetId().GetValue() != "recent" {
+		t.Fatal("Framework ID within the failover timeout should have been reused")
+	}
+
+	info.Id = nil
+	storeId(kv, "stale", time.Now().Add(-2*time.Minute))
+	if err := f.Load(info); err != nil || info.Id != nil {
+		t.Fatal("Framework ID past the failover timeout should not have been reused")
+	}
+
+	if storedId(t, kv) != "" {
+		t.Fatal("Stale framework ID should have been removed")
+	}
+}
+
+// Checks that IDs are saved, refreshed and cleared.
+func TestFrameworkIdStore_Save(t *testing.T) {
+	t.Parallel()
+
+	kv := &memoryKVStore{data: make(map[string]string)}
+	f := NewFrameworkIdStore(kv, "", l)
+
+	if err := f.Save(&mesos_v1.FrameworkID{Value: proto.String("framework")}); err != nil {
+		t.Fatal(err.Error())
+	}
+	if storedId(t, kv) != "framework" {
+		t.Fatal("Framework ID was not stored")
+	}
+
+	storeId(kv, "framework", time.Now().Add(-time.Hour))
+	f.refresh = 0
+	f.Heartbeat()
+
+	info := &mesos_v1.FrameworkInfo{FailoverTimeout: proto.Float64(60)}
+	f.Load(info)
+	if info.GetId().GetValue() != "framework" {
+		t.Fatal("Heartbeat should have refreshed the stored framework ID")
+	}
+
+	f.Clear()
+	if storedId(t, kv) != "" {
+		t.Fatal("Framework ID should have been cleared")
+	}
+}
+
+// Ensures the supervisor resumes with the stored ID and keeps the one Mesos hands back.
+func TestSubscriptionSupervisor_FrameworkIds(t *testing.T) {
+	t.Parallel()
+
+	kv := &memoryKVStore{data: make(map[string]string)}
+	storeId(kv, "stored", time.Now())
+
+	s := newSilentScheduler(&mesos_v1.FrameworkInfo{FailoverTimeout: proto.Float64(60)}, 1)
+	events := make(chan *mesos_v1_scheduler.Event, 1)
+	sup := NewSubscriptionSupervisor(s, events, SupervisorConfig{
+		FrameworkIds: NewFrameworkIdStore(kv, "", l),
+	}, l)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	result := make(chan error)
+	go func() {
+		result <- sup.Run(ctx)
+	}()
+
+	if id := <-s.subscriptions; id.GetValue() != "stored" {
+		t.Fatal("Subscription should have used the stored framework ID")
+	}
+
+	<-events
+	cancel()
+	<-result
+
+	if storedId(t, kv) != "framework" {
+		t.Fatal("Framework ID from Mesos should have been stored")
+	}
+}
+
+// Tests detection of removed frameworks.
+func TestIsFrameworkRemoved(t *testing.T) {
+	t.Parallel()
+
+	if !IsFrameworkRemoved("Framework has been removed") || IsFrameworkRemoved("Framework failed over") {
+		t.Fatal("Removed frameworks are not detected correctly")
+	}
+}
@@ -0,0 +1,216 @@
+// Copyright 2017 Verizon
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package scheduler
+
+import (
+	"github.com/verizonlabs/mesos-framework-sdk/include/mesos_v1"
+	"github.com/verizonlabs/mesos-framework-sdk/logging"
+	"github.com/verizonlabs/mesos-framework-sdk/task/manager"
+	"sync"
+	"time"
+)
+
+/*
+MaintenanceDrainer cooperates with operator maintenance windows.
+
+Mesos sends inverse offers for agents that are scheduled to go down.
+The drainer kills our tasks on those agents and only accepts the inverse offer once all of them have terminated,
+letting the operator know it's safe to go ahead.
+Killed tasks are reported through the usual status updates, so they can be rescheduled elsewhere as normal.
+Agents stay drained until their unavailability is over or the inverse offer is rescinded, so nothing lands back on them.
+
+Hook it up by passing inverse offers, status updates and rescinded inverse offers from the event handlers.
+*/
+type MaintenanceDrainer struct {
+	scheduler Scheduler
+	tasks     manager.TaskManager
+	logger    logging.Logger
+	drains    map[string]*drain // Keyed by inverse offer ID.
+	lock      sync.Mutex
+}
+
+// Tasks we're still waiting on before an inverse offer can be accepted.
+type drain struct {
+	offerId  *mesos_v1.OfferID
+	agentId  *mesos_v1.AgentID
+	tasks    map[string]bool
+	accepted bool
+	until    time.Time // Zero when the unavailability has no end.
+}
+
+func NewMaintenanceDrainer(s Scheduler, tasks manager.TaskManager, logger logging.Logger) *MaintenanceDrainer {
+	return &MaintenanceDrainer{
+		scheduler: s,
+		tasks:     tasks,
+		logger:    logger,
+		drains:    make(map[string]*drain),
+	}
+}
+
+// Starts draining the agents described by the inverse offers.
+// Inverse offers without an agent apply to all of our tasks.
+func (d *MaintenanceDrainer) InverseOffers(offers []*mesos_v1.InverseOffer) {
+	tasks, err := d.tasks.All()
+	if err != nil {
+		d.logger.Emit(logging.ERROR, "Failed to get tasks to drain: %s", err.Error())
+		return
+	}
+
+	var ready []*mesos_v1.OfferID
+	var kill []*mesos_v1.TaskInfo
+
+	d.lock.Lock()
+	d.prune()
+	for _, offer := range offers {
+		if _, ok := d.drains[offer.GetId().GetValue()]; ok {
+			continue
+		}
+
+		dr := &drain{
+			offerId: offer.GetId(),
+			agentId: offer.GetAgentId(),
+			tasks:   make(map[string]bool),
+			until:   unavailableUntil(offer.GetUnavailability()),
+		}
+
+		for _, t := range tasks {
+			if t.Info == nil || isTerminal(t.State) || !dr.covers(t.Info.GetAgentId()) {
+				continue
+			}
+
+			dr.tasks[t.Info.GetTaskId().GetValue()] = true
+			kill = append(kill, t.Info)
+		}
+
+		d.drains[dr.offerId.GetValue()] = dr
+		if len(dr.tasks) == 0 {
+			dr.accepted = true
+			ready = append(ready, dr.offerId)
+			continue
+		}
+
+		d.logger.Emit(logging.INFO, "Draining %d tasks from agent %s for maintenance", len(dr.tasks), dr.agentId.GetValue())
+	}
+	d.lock.Unlock()
+
+	for _, t := range kill {
+		resp, err := d.scheduler.Kill(t.GetTaskId(), t.GetAgentId())
+		if err != nil {
+			d.logger.Emit(logging.ERROR, "Failed to kill task %s for maintenance: %s", t.GetTaskId().GetValue(), err.Error())
+		}
+		closeBody(resp)
+	}
+
+	d.accept(ready)
+}
+
+// Tracks tasks terminating so that inverse offers can be accepted once their agents are drained.
+func (d *MaintenanceDrainer) Update(status *mesos_v1.TaskStatus) {
+	if !isTerminal(status.GetState()) {
+		return
+	}
+
+	var ready []*mesos_v1.OfferID
+	id := status.GetTaskId().GetValue()
+
+	d.lock.Lock()
+	for _, dr := range d.drains {
+		if !dr.tasks[id] {
+			continue
+		}
+
+		delete(dr.tasks, id)
+		if len(dr.tasks) == 0 && !dr.accepted {
+			dr.accepted = true
+			ready = append(ready, dr.offerId)
+		}
+	}
+	d.lock.Unlock()
+
+	d.accept(ready)
+}
+
+// Stops tracking an inverse offer that Mesos no longer cares about, letting tasks back onto its agent.
+func (d *MaintenanceDrainer) Rescind(offerId *mesos_v1.OfferID) {
+	d.lock.Lock()
+	defer d.lock.Unlock()
+
+	delete(d.drains, offerId.GetValue())
+}
+
+// Tells us if an agent is being drained, in which case no new tasks should be placed on it.
+// Hand this to the resource manager through ResourceManagerConfig.Draining to keep killed tasks off of the agent.
+func (d *MaintenanceDrainer) Draining(agentId *mesos_v1.AgentID) bool {
+	d.lock.Lock()
+	defer d.lock.Unlock()
+
+	d.prune()
+	for _, dr := range d.drains {
+		if dr.covers(agentId) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Forgets about drains whose unavailability has passed.
+// Must be called with the lock held.
+func (d *MaintenanceDrainer) prune() {
+	now := time.Now()
+	for key, dr := range d.drains {
+		if !dr.until.IsZero() && now.After(dr.until) {
+			delete(d.drains, key)
+		}
+	}
+}
+
+// Accepts inverse offers whose agents have been fully drained.
+func (d *MaintenanceDrainer) accept(offerIds []*mesos_v1.OfferID) {
+	if len(offerIds) == 0 {
+		return
+	}
+
+	resp, err := d.scheduler.AcceptInverseOffers(offerIds, nil)
+	if err != nil {
+		d.logger.Emit(logging.ERROR, "Failed to accept inverse offers: %s", err.Error())
+	}
+	closeBody(resp)
+}
+
+// Checks if the agent is affected by the inverse offer.
+func (dr *drain) covers(agentId *mesos_v1.AgentID) bool {
+	return dr.agentId == nil || dr.agentId.GetValue() == agentId.GetValue()
+}
+
+// Works out when an agent comes back from maintenance, or the zero time if it never does.
+func unavailableUntil(u *mesos_v1.Unavailability) time.Time {
+	if u.GetDuration() == nil {
+		return time.Time{}
+	}
+
+	return time.Unix(0, u.GetStart().GetNanoseconds()+u.GetDuration().GetNanoseconds())
+}
+
+// Tells us if a task has stopped for good.
+func isTerminal(state mesos_v1.TaskState) bool {
+	switch state {
+	case manager.FINISHED, manager.FAILED, manager.KILLED, manager.ERROR, manager.LOST,
+		manager.DROPPED, manager.GONE, manager.GONE_BY_OPERATOR:
+		return true
+	}
+
+	return false
+}
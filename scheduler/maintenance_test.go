@@ -0,0 +1,193 @@
+// Copyright 2017 Verizon
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package scheduler
+
+import (
+	"github.com/golang/protobuf/proto"
+	"github.com/verizonlabs/mesos-framework-sdk/include/mesos_v1"
+	"github.com/verizonlabs/mesos-framework-sdk/include/mesos_v1_scheduler"
+	"github.com/verizonlabs/mesos-framework-sdk/task/manager"
+	"testing"
+	"time"
+)
+
+// Only hands back a fixed set of tasks.
+type mockTaskManager struct {
+	manager.TaskManager
+	tasks []*manager.Task
+}
+
+func (m *mockTaskManager) All() ([]*manager.Task, error) {
+	return m.tasks, nil
+}
+
+func mockTask(id, agent string, state mesos_v1.TaskState) *manager.Task {
+	return &manager.Task{
+		Info: &mesos_v1.TaskInfo{
+			TaskId:  &mesos_v1.TaskID{Value: proto.String(id)},
+			AgentId: &mesos_v1.AgentID{Value: proto.String(agent)},
+		},
+		State: state,
+	}
+}
+
+// Makes sure inverse offers are only accepted once the agent has been drained.
+func TestMaintenanceDrainer_InverseOffers(t *testing.T) {
+	t.Parallel()
+
+	r := new(recordingClient)
+	d := NewMaintenanceDrainer(NewDefaultScheduler(r, i, l), &mockTaskManager{
+		tasks: []*manager.Task{
+			mockTask("a", "maintenance", manager.RUNNING),
+			mockTask("b", "maintenance", manager.RUNNING),
+			mockTask("c", "maintenance", manager.FINISHED),
+			mockTask("d", "other", manager.RUNNING),
+		},
+	}, l)
+
+	agent := &mesos_v1.AgentID{Value: proto.String("maintenance")}
+	d.InverseOffers([]*mesos_v1.InverseOffer{
+		{
+			Id:      &mesos_v1.OfferID{Value: proto.String("inverse")},
+			AgentId: agent,
+		},
+	})
+
+	if len(r.byType(mesos_v1_scheduler.Call_KILL)) != 2 {
+		t.Fatal("Only running tasks on the agent should have been killed")
+	}
+
+	if !d.Draining(agent) || d.Draining(&mesos_v1.AgentID{Value: proto.String("other")}) {
+		t.Fatal("Only the agent under maintenance should be draining")
+	}
+
+	d.Update(&mesos_v1.TaskStatus{TaskId: &mesos_v1.TaskID{Value: proto.String("a")}, State: manager.KILLED.Enum()})
+	d.Update(&mesos_v1.TaskStatus{TaskId: &mesos_v1.TaskID{Value: proto.String("b")}, State: manager.KILLING.Enum()})
+	if len(r.byType(mesos_v1_scheduler.Call_ACCEPT_INVERSE_OFFERS)) != 0 {
+		t.Fatal("Inverse offer was accepted before the agent was drained")
+	}
+
+	d.Update(&mesos_v1.TaskStatus{TaskId: &mesos_v1.TaskID{Value: proto.String("b")}, State: manager.KILLED.Enum()})
+	accepted := r.byType(mesos_v1_scheduler.Call_ACCEPT_INVERSE_OFFERS)
+	if len(accepted) != 1 || accepted[0].AcceptInverseOffers.InverseOfferIds[0].GetValue() != "inverse" {
+		t.Fatal("Inverse offer should have been accepted once the agent was drained")
+	}
+
+	if !d.Draining(agent) {
+		t.Fatal("Agent should keep draining until its maintenance is over")
+	}
+}
+
+// Checks that agents come back once their unavailability has passed.
+func TestMaintenanceDrainer_Unavailability(t *testing.T) {
+	t.Parallel()
+
+	r := new(recordingClient)
+	d := NewMaintenanceDrainer(NewDefaultScheduler(r, i, l), new(mockTaskManager), l)
+
+	window := func(start time.Time, length time.Duration) *mesos_v1.Unavailability {
+		return &mesos_v1.Unavailability{
+			Start:    &mesos_v1.TimeInfo{Nanoseconds: proto.Int64(start.UnixNano())},
+			Duration: &mesos_v1.DurationInfo{Nanoseconds: proto.Int64(int64(length))},
+		}
+	}
+
+	d.InverseOffers([]*mesos_v1.InverseOffer{
+		{
+			Id:             &mesos_v1.OfferID{Value: proto.String("upcoming")},
+			AgentId:        &mesos_v1.AgentID{Value: proto.String("upcoming")},
+			Unavailability: window(time.Now(), time.Hour),
+		},
+		{
+			Id:             &mesos_v1.OfferID{Value: proto.String("over")},
+			AgentId:        &mesos_v1.AgentID{Value: proto.String("over")},
+			Unavailability: window(time.Now().Add(-2*time.Hour), time.Hour),
+		},
+	})
+
+	if !d.Draining(&mesos_v1.AgentID{Value: proto.String("upcoming")}) {
+		t.Fatal("Agent should be draining during its maintenance window")
+	}
+
+	if d.Draining(&mesos_v1.AgentID{Value: proto.String("over")}) {
+		t.Fatal("Agent should be back once its maintenance window has passed")
+	}
+}
+
+// Ensures agents without any of our tasks are accepted right away and rescinded offers are forgotten.
+func TestMaintenanceDrainer_Rescind(t *testing.T) {
+	t.Parallel()
+
+	r := new(recordingClient)
+	d := NewMaintenanceDrainer(NewDefaultScheduler(r, i, l), &mockTaskManager{
+		tasks: []*manager.Task{mockTask("a", "busy", manager.RUNNING)},
+	}, l)
+
+	d.InverseOffers([]*mesos_v1.InverseOffer{
+		{
+			Id:      &mesos_v1.OfferID{Value: proto.String("idle")},
+			AgentId: &mesos_v1.AgentID{Value: proto.String("idle")},
+		},
+		{
+			Id:      &mesos_v1.OfferID{Value: proto.String("busy")},
+			AgentId: &mesos_v1.AgentID{Value: proto.String("busy")},
+		},
+	})
+
+	if len(r.byType(mesos_v1_scheduler.Call_ACCEPT_INVERSE_OFFERS)) != 1 {
+		t.Fatal("Inverse offer for an idle agent should have been accepted right away")
+	}
+
+	idle := &mesos_v1.AgentID{Value: proto.String("idle")}
+	if !d.Draining(idle) {
+		t.Fatal("Idle agent should still be kept free of new tasks")
+	}
+
+	d.Rescind(&mesos_v1.OfferID{Value: proto.String("busy")})
+	d.Update(&mesos_v1.TaskStatus{TaskId: &mesos_v1.TaskID{Value: proto.String("a")}, State: manager.KILLED.Enum()})
+	if len(r.byType(mesos_v1_scheduler.Call_ACCEPT_INVERSE_OFFERS)) != 1 {
+		t.Fatal("Rescinded inverse offer should not have been accepted")
+	}
+
+	if d.Draining(&mesos_v1.AgentID{Value: proto.String("busy")}) {
+		t.Fatal("Agent should no longer be draining once its inverse offer is rescinded")
+	}
+
+	d.Rescind(&mesos_v1.OfferID{Value: proto.String("idle")})
+	if d.Draining(idle) {
+		t.Fatal("Idle agent should be usable again once its inverse offer is rescinded")
+	}
+}
+
+// Tests our inverse offer calls to Mesos.
+func TestDefaultScheduler_InverseOffers(t *testing.T) {
+	t.Parallel()
+
+	r := new(recordingClient)
+	s := NewDefaultScheduler(r, i, l)
+	ids := []*mesos_v1.OfferID{{Value: proto.String("inverse")}}
+
+	if _, err := s.AcceptInverseOffers(ids, nil); err != nil {
+		t.Fatal(err.Error())
+	}
+	if _, err := s.DeclineInverseOffers(ids, nil); err != nil {
+		t.Fatal(err.Error())
+	}
+
+	declined := r.byType(mesos_v1_scheduler.Call_DECLINE_INVERSE_OFFERS)
+	if len(r.byType(mesos_v1_scheduler.Call_ACCEPT_INVERSE_OFFERS)) != 1 || len(declined) != 1 || len(declined[0].DeclineInverseOffers.InverseOfferIds) != 1 {
+		t.Fatal("Inverse offer calls were not made correctly")
+	}
+}
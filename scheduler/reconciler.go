@@ -0,0 +1,233 @@
+// Copyright 2017 Verizon
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package scheduler
+
+import (
+	"context"
+	"github.com/verizonlabs/mesos-framework-sdk/include/mesos_v1"
+	"github.com/verizonlabs/mesos-framework-sdk/logging"
+	"github.com/verizonlabs/mesos-framework-sdk/task/manager"
+	"sync"
+	"time"
+)
+
+/*
+Reconciler makes sure our view of tasks matches what Mesos knows about them.
+
+Each round explicitly reconciles every launched task in the task manager that hasn't terminated.
+Tasks that don't get a status update are asked about again with exponential backoff.
+Once we run out of attempts we fall back to implicit reconciliation, which has Mesos send us the state of every
+task it knows about. Anything that still doesn't answer is marked as LOST in the task manager.
+
+Rounds should be started whenever we subscribe, and Run takes care of starting them periodically as well.
+Status updates need to be passed along from the event handler so we know which tasks have answered.
+*/
+type Reconciler struct {
+	scheduler Scheduler
+	tasks     manager.TaskManager
+	config    ReconcilerConfig
+	logger    logging.Logger
+	pending   map[string]*manager.Task // Tasks we haven't heard back from in the current round.
+	trigger   chan struct{}
+	lock      sync.Mutex
+	round     sync.Mutex
+}
+
+// Round timing for the reconciler.
+// Left empty, rounds start every 15 minutes with up to 5 explicit attempts, waiting a second after the first one and
+// backing off to at most 30 times that.
+type ReconcilerConfig struct {
+	Interval       time.Duration // How often Run starts a new round.
+	InitialBackoff time.Duration // How long we wait for status updates after the first attempt.
+	MaxBackoff     time.Duration
+	MaxAttempts    int // Number of explicit attempts before falling back to implicit reconciliation.
+}
+
+func NewReconciler(s Scheduler, tasks manager.TaskManager, config ReconcilerConfig, logger logging.Logger) *Reconciler {
+	if config.Interval <= 0 {
+		config.Interval = 15 * time.Minute
+	}
+	if config.InitialBackoff <= 0 {
+		config.InitialBackoff = time.Second
+	}
+	if config.MaxBackoff < config.InitialBackoff {
+		config.MaxBackoff = 30 * config.InitialBackoff
+	}
+	if config.MaxAttempts <= 0 {
+		config.MaxAttempts = 5
+	}
+
+	return &Reconciler{
+		scheduler: s,
+		tasks:     tasks,
+		config:    config,
+		logger:    logger,
+		pending:   make(map[string]*manager.Task),
+		trigger:   make(chan struct{}, 1),
+	}
+}
+
+// Reconciles right away and then periodically until the context is done.
+// Trigger starts a round early, such as after resubscribing.
+func (r *Reconciler) Run(ctx context.Context) error {
+	ticker := time.NewTicker(r.config.Interval)
+	defer ticker.Stop()
+
+	for {
+		if err := r.Reconcile(ctx); err != nil && ctx.Err() != nil {
+			return ctx.Err()
+		}
+
+		select {
+		case <-ticker.C:
+		case <-r.trigger:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
+
+// Asks Run to start a new round as soon as possible.
+// Calling this while a round is already queued does nothing.
+func (r *Reconciler) Trigger() {
+	select {
+	case r.trigger <- struct{}{}:
+	default:
+	}
+}
+
+// Records that Mesos told us about a task.
+func (r *Reconciler) Update(status *mesos_v1.TaskStatus) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	delete(r.pending, status.GetTaskId().GetValue())
+}
+
+// Runs a single round of reconciliation, returning once every task has answered or been marked as lost.
+func (r *Reconciler) Reconcile(ctx context.Context) error {
+	r.round.Lock()
+	defer r.round.Unlock()
+
+	tasks, err := r.tasks.All()
+	if err != nil {
+		r.logger.Emit(logging.ERROR, "Failed to get tasks to reconcile: %s", err.Error())
+		return err
+	}
+
+	r.lock.Lock()
+	r.pending = make(map[string]*manager.Task)
+	for _, t := range tasks {
+
+		// Tasks that were never launched aren't known to Mesos yet.
+		if t.Info == nil || t.Info.AgentId == nil || isTerminal(t.State) {
+			continue
+		}
+		r.pending[t.Info.GetTaskId().GetValue()] = t
+	}
+	r.lock.Unlock()
+
+	backoff := r.config.InitialBackoff
+	for attempt := 1; attempt <= r.config.MaxAttempts; attempt++ {
+		infos := r.waiting()
+		if len(infos) == 0 {
+			return nil
+		}
+
+		r.logger.Emit(logging.INFO, "Explicitly reconciling %d tasks, attempt %d", len(infos), attempt)
+		resp, err := r.scheduler.ReconcileContext(ctx, infos)
+		closeBody(resp)
+		if err != nil {
+			r.logger.Emit(logging.ERROR, "Explicit reconciliation failed, giving up on this round: %s", err.Error())
+			return err
+		}
+
+		if err := r.wait(ctx, backoff); err != nil {
+			return err
+		}
+
+		backoff *= 2
+		if backoff > r.config.MaxBackoff {
+			backoff = r.config.MaxBackoff
+		}
+	}
+
+	if len(r.waiting()) == 0 {
+		return nil
+	}
+
+	r.logger.Emit(logging.INFO, "Falling back to implicit reconciliation")
+	resp, err := r.scheduler.ReconcileContext(ctx, nil)
+	closeBody(resp)
+	if err != nil {
+		r.logger.Emit(logging.ERROR, "Implicit reconciliation failed, giving up on this round: %s", err.Error())
+		return err
+	}
+
+	if err := r.wait(ctx, backoff); err != nil {
+		return err
+	}
+
+	r.lock.Lock()
+	lost := make([]*manager.Task, 0, len(r.pending))
+	for _, t := range r.pending {
+		lost = append(lost, t)
+	}
+	r.pending = make(map[string]*manager.Task)
+	r.lock.Unlock()
+
+	for _, t := range lost {
+		r.logger.Emit(logging.ERROR, "Task %s never answered reconciliation, marking it as lost", t.Info.GetTaskId().GetValue())
+		if err := r.tasks.Update(lostCopy(t)); err != nil {
+			r.logger.Emit(logging.ERROR, "Failed to mark task %s as lost: %s", t.Info.GetTaskId().GetValue(), err.Error())
+		}
+	}
+
+	return nil
+}
+
+// Copies a task in the lost state so the one the task manager handed us is left alone until it records the change.
+func lostCopy(t *manager.Task) *manager.Task {
+	lost := manager.NewTask(t.Info, manager.LOST, t.Filters, t.Retry, t.Instances, t.GroupInfo)
+	lost.IsKill = t.IsKill
+	lost.Strategy = t.Strategy
+	lost.Role = t.Role
+	lost.Requested = t.Requested
+
+	return lost
+}
+
+// Gets the tasks we're still waiting to hear back from.
+func (r *Reconciler) waiting() []*mesos_v1.TaskInfo {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	infos := make([]*mesos_v1.TaskInfo, 0, len(r.pending))
+	for _, t := range r.pending {
+		infos = append(infos, t.Info)
+	}
+
+	return infos
+}
+
+// Gives Mesos time to send status updates.
+func (r *Reconciler) wait(ctx context.Context, d time.Duration) error {
+	select {
+	case <-time.After(d):
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
@@ -0,0 +1,172 @@
+// Copyright 2017 Verizon
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package scheduler
+
+import (
+	"context"
+	"errors"
+	"github.com/golang/protobuf/proto"
+	"github.com/verizonlabs/mesos-framework-sdk/include/mesos_v1"
+	"github.com/verizonlabs/mesos-framework-sdk/include/mesos_v1_scheduler"
+	"github.com/verizonlabs/mesos-framework-sdk/task/manager"
+	"testing"
+	"time"
+)
+
+// Keeps track of the tasks the reconciler updates.
+type updatingTaskManager struct {
+	mockTaskManager
+	updated []*manager.Task
+}
+
+func (u *updatingTaskManager) Update(tasks ...*manager.Task) error {
+	u.updated = append(u.updated, tasks...)
+	return nil
+}
+
+// Answers reconciliation for a single task, like a master that lost track of the others.
+func answering(r *Reconciler, task string) func(*mesos_v1_scheduler.Call) error {
+	return func(*mesos_v1_scheduler.Call) error {
+		r.Update(&mesos_v1.TaskStatus{
+			TaskId: &mesos_v1.TaskID{Value: proto.String(task)},
+			State:  manager.RUNNING.Enum(),
+		})
+
+		return nil
+	}
+}
+
+// Makes sure tasks that never answer are retried, implicitly reconciled and finally marked as lost.
+func TestReconciler_Reconcile(t *testing.T) {
+	t.Parallel()
+
+	tasks := &updatingTaskManager{mockTaskManager: mockTaskManager{
+		tasks: []*manager.Task{
+			mockTask("answers", "agent", manager.RUNNING),
+			mockTask("silent", "agent", manager.STAGING),
+			mockTask("finished", "agent", manager.FINISHED),
+			{Info: &mesos_v1.TaskInfo{TaskId: &mesos_v1.TaskID{Value: proto.String("unlaunched")}}},
+		},
+	}}
+
+	c := new(recordingClient)
+	r := NewReconciler(NewDefaultScheduler(c, i, l), tasks, ReconcilerConfig{
+		InitialBackoff: time.Millisecond,
+		MaxBackoff:     2 * time.Millisecond,
+		MaxAttempts:    3,
+	}, l)
+	c.onCall = answering(r, "answers")
+
+	if err := r.Reconcile(context.Background()); err != nil {
+		t.Fatal(err.Error())
+	}
+
+	calls := c.byType(mesos_v1_scheduler.Call_RECONCILE)
+	if len(calls) != 4 {
+		t.Fatalf("Expected 3 explicit attempts and 1 implicit one, got %d calls", len(calls))
+	}
+
+	if len(calls[0].Reconcile.Tasks) != 2 || len(calls[1].Reconcile.Tasks) != 1 || len(calls[3].Reconcile.Tasks) != 0 {
+		t.Fatal("Only tasks we're waiting on should be reconciled")
+	}
+
+	if len(tasks.updated) != 1 || tasks.updated[0].Info.GetTaskId().GetValue() != "silent" || tasks.updated[0].State != manager.LOST {
+		t.Fatal("Silent task should have been marked as lost")
+	}
+
+	if tasks.tasks[1].State != manager.STAGING {
+		t.Fatal("The lost state should go through the task manager instead of changing the task we were given")
+	}
+}
+
+// Makes sure a failed call aborts the round instead of marking tasks as lost.
+func TestReconciler_ReconcileFailure(t *testing.T) {
+	t.Parallel()
+
+	implicit := func(call *mesos_v1_scheduler.Call) error {
+		if len(call.Reconcile.Tasks) == 0 {
+			return errors.New("Master unavailable")
+		}
+
+		return nil
+	}
+
+	for calls, onCall := range map[int]func(*mesos_v1_scheduler.Call) error{1: failing(1), 4: implicit} {
+		tasks := &updatingTaskManager{mockTaskManager: mockTaskManager{
+			tasks: []*manager.Task{mockTask("silent", "agent", manager.RUNNING)},
+		}}
+
+		c := &recordingClient{onCall: onCall}
+		r := NewReconciler(NewDefaultScheduler(c, i, l), tasks, ReconcilerConfig{
+			InitialBackoff: time.Millisecond,
+			MaxAttempts:    3,
+		}, l)
+
+		if err := r.Reconcile(context.Background()); err == nil {
+			t.Fatal("A failed call should fail the round")
+		}
+
+		if len(c.byType(mesos_v1_scheduler.Call_RECONCILE)) != calls || len(tasks.updated) != 0 {
+			t.Fatalf("Nothing should be marked as lost once call %d fails", calls)
+		}
+	}
+}
+
+// Ensures a round stops early once everybody has answered.
+func TestReconciler_ReconcileAnswered(t *testing.T) {
+	t.Parallel()
+
+	tasks := &updatingTaskManager{mockTaskManager: mockTaskManager{
+		tasks: []*manager.Task{mockTask("answers", "agent", manager.RUNNING)},
+	}}
+
+	c := new(recordingClient)
+	r := NewReconciler(NewDefaultScheduler(c, i, l), tasks, ReconcilerConfig{InitialBackoff: time.Millisecond}, l)
+	c.onCall = answering(r, "answers")
+
+	r.Reconcile(context.Background())
+	if len(c.byType(mesos_v1_scheduler.Call_RECONCILE)) != 1 || len(tasks.updated) != 0 {
+		t.Fatal("Reconciliation should have stopped after everybody answered")
+	}
+}
+
+// Checks that Run keeps reconciling when triggered and stops along with its context.
+func TestReconciler_Run(t *testing.T) {
+	t.Parallel()
+
+	tasks := &updatingTaskManager{mockTaskManager: mockTaskManager{
+		tasks: []*manager.Task{mockTask("answers", "agent", manager.RUNNING)},
+	}}
+
+	c := new(recordingClient)
+	r := NewReconciler(NewDefaultScheduler(c, i, l), tasks, ReconcilerConfig{InitialBackoff: time.Millisecond}, l)
+	c.onCall = answering(r, "answers")
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error)
+	go func() {
+		done <- r.Run(ctx)
+	}()
+
+	r.Trigger()
+	for len(c.byType(mesos_v1_scheduler.Call_RECONCILE)) < 2 {
+		time.Sleep(time.Millisecond)
+	}
+
+	cancel()
+	if <-done != context.Canceled {
+		t.Fatal("Run should have stopped once cancelled")
+	}
+}
@@ -0,0 +1,45 @@
+// Copyright 2017 Verizon
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package scheduler
+
+import (
+	"github.com/verizonlabs/mesos-framework-sdk/include/mesos_v1"
+)
+
+// Subscribes the framework to several roles at once.
+// Mesos doesn't allow the legacy role field to be set alongside roles, so it's cleared.
+func EnableMultiRole(info *mesos_v1.FrameworkInfo, roles ...string) {
+	info.Role = nil
+	info.Roles = roles
+
+	for _, c := range info.Capabilities {
+		if c.GetType() == mesos_v1.FrameworkInfo_Capability_MULTI_ROLE {
+			return
+		}
+	}
+
+	info.Capabilities = append(info.Capabilities, &mesos_v1.FrameworkInfo_Capability{
+		Type: mesos_v1.FrameworkInfo_Capability_MULTI_ROLE.Enum(),
+	})
+}
+
+// Gets every role the framework is subscribed to, whether or not it's multi-role.
+func FrameworkRoles(info *mesos_v1.FrameworkInfo) []string {
+	if len(info.GetRoles()) > 0 {
+		return info.GetRoles()
+	}
+
+	return []string{info.GetRole()}
+}
@@ -0,0 +1,42 @@
+// Copyright 2017 Verizon
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package scheduler
+
+import (
+	"github.com/golang/protobuf/proto"
+	"github.com/verizonlabs/mesos-framework-sdk/include/mesos_v1"
+	"testing"
+)
+
+// Makes sure frameworks are set up properly for multiple roles.
+func TestEnableMultiRole(t *testing.T) {
+	t.Parallel()
+
+	info := &mesos_v1.FrameworkInfo{Role: proto.String("legacy")}
+	if roles := FrameworkRoles(info); len(roles) != 1 || roles[0] != "legacy" {
+		t.Fatal("Legacy role should be used when the framework isn't multi-role")
+	}
+
+	EnableMultiRole(info, "web", "db")
+	EnableMultiRole(info, "web", "db")
+
+	if info.Role != nil || len(info.Capabilities) != 1 || info.Capabilities[0].GetType() != mesos_v1.FrameworkInfo_Capability_MULTI_ROLE {
+		t.Fatal("Framework should only have the multi-role capability")
+	}
+
+	if roles := FrameworkRoles(info); len(roles) != 2 || roles[1] != "db" {
+		t.Fatal("Framework should be subscribed to every role")
+	}
+}
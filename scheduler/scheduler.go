@@ -26,7 +26,9 @@ the protobuf required for the call and send it off to the client.
 End users should only create their own scheduler if they wish to change the behavior of their calls.
 */
 import (
+	"context"
 	"errors"
+	"github.com/golang/protobuf/proto"
 	"github.com/verizonlabs/mesos-framework-sdk/client"
 	"github.com/verizonlabs/mesos-framework-sdk/include/mesos_v1"
 	sched "github.com/verizonlabs/mesos-framework-sdk/include/mesos_v1_scheduler"
@@ -38,6 +40,7 @@ import (
 
 type Scheduler interface {
 	FrameworkInfo() *mesos_v1.FrameworkInfo
+	SetFrameworkId(id *mesos_v1.FrameworkID)
 
 	// Default Calls for scheduler
 	Subscribe(chan *sched.Event) (*http.Response, error)
@@ -46,12 +49,36 @@ type Scheduler interface {
 	Decline(offerIds []*mesos_v1.OfferID, filters *mesos_v1.Filters) (*http.Response, error)
 	Revive() (*http.Response, error)
 	Kill(taskId *mesos_v1.TaskID, agentid *mesos_v1.AgentID) (*http.Response, error)
+	KillWithPolicy(taskId *mesos_v1.TaskID, agentid *mesos_v1.AgentID, policy *mesos_v1.KillPolicy) (*http.Response, error)
 	Shutdown(execId *mesos_v1.ExecutorID, agentId *mesos_v1.AgentID) (*http.Response, error)
 	Acknowledge(agentId *mesos_v1.AgentID, taskId *mesos_v1.TaskID, uuid []byte) (*http.Response, error)
 	Reconcile(tasks []*mesos_v1.TaskInfo) (*http.Response, error)
 	Message(agentId *mesos_v1.AgentID, executorId *mesos_v1.ExecutorID, data []byte) (*http.Response, error)
 	SchedRequest(resources []*mesos_v1.Request) (*http.Response, error)
 	Suppress() (*http.Response, error)
+	SuppressRoles(roles []string) (*http.Response, error)
+	ReviveRoles(roles []string) (*http.Response, error)
+	AcceptInverseOffers(offerIds []*mesos_v1.OfferID, filters *mesos_v1.Filters) (*http.Response, error)
+	DeclineInverseOffers(offerIds []*mesos_v1.OfferID, filters *mesos_v1.Filters) (*http.Response, error)
+
+	// Same calls as above, cancelled along with the given context.
+	SubscribeContext(ctx context.Context, events chan *sched.Event) (*http.Response, error)
+	TeardownContext(ctx context.Context) (*http.Response, error)
+	AcceptContext(ctx context.Context, offerIds []*mesos_v1.OfferID, tasks []*mesos_v1.Offer_Operation, filters *mesos_v1.Filters) (*http.Response, error)
+	DeclineContext(ctx context.Context, offerIds []*mesos_v1.OfferID, filters *mesos_v1.Filters) (*http.Response, error)
+	ReviveContext(ctx context.Context) (*http.Response, error)
+	KillContext(ctx context.Context, taskId *mesos_v1.TaskID, agentid *mesos_v1.AgentID) (*http.Response, error)
+	KillWithPolicyContext(ctx context.Context, taskId *mesos_v1.TaskID, agentid *mesos_v1.AgentID, policy *mesos_v1.KillPolicy) (*http.Response, error)
+	ShutdownContext(ctx context.Context, execId *mesos_v1.ExecutorID, agentId *mesos_v1.AgentID) (*http.Response, error)
+	AcknowledgeContext(ctx context.Context, agentId *mesos_v1.AgentID, taskId *mesos_v1.TaskID, uuid []byte) (*http.Response, error)
+	ReconcileContext(ctx context.Context, tasks []*mesos_v1.TaskInfo) (*http.Response, error)
+	MessageContext(ctx context.Context, agentId *mesos_v1.AgentID, executorId *mesos_v1.ExecutorID, data []byte) (*http.Response, error)
+	SchedRequestContext(ctx context.Context, resources []*mesos_v1.Request) (*http.Response, error)
+	SuppressContext(ctx context.Context) (*http.Response, error)
+	SuppressRolesContext(ctx context.Context, roles []string) (*http.Response, error)
+	ReviveRolesContext(ctx context.Context, roles []string) (*http.Response, error)
+	AcceptInverseOffersContext(ctx context.Context, offerIds []*mesos_v1.OfferID, filters *mesos_v1.Filters) (*http.Response, error)
+	DeclineInverseOffersContext(ctx context.Context, offerIds []*mesos_v1.OfferID, filters *mesos_v1.Filters) (*http.Response, error)
 }
 
 // Default Scheduler can be used as a higher-level construct.
@@ -60,6 +87,7 @@ type DefaultScheduler struct {
 	Client        client.Client
 	logger        logging.Logger
 	IsSuppressed  bool
+	suppressed    map[string]bool // Roles suppressed on their own through SuppressRoles.
 	sync.RWMutex
 }
 
@@ -69,44 +97,75 @@ func NewDefaultScheduler(c client.Client, info *mesos_v1.FrameworkInfo, logger l
 		frameworkInfo: info,
 		logger:        logger,
 		IsSuppressed:  false,
+		suppressed:    make(map[string]bool),
 	}
 }
 
 func (c *DefaultScheduler) FrameworkInfo() *mesos_v1.FrameworkInfo {
+	c.RLock()
+	defer c.RUnlock()
+
 	return c.frameworkInfo
 }
 
+// Changes the ID we subscribe and make calls with, nil registers us as a new framework next time we subscribe.
+// Calls already being sent hold on to the framework info they started with, so it's copied rather than changed in place.
+func (c *DefaultScheduler) SetFrameworkId(id *mesos_v1.FrameworkID) {
+	c.Lock()
+	defer c.Unlock()
+
+	info := proto.Clone(c.frameworkInfo).(*mesos_v1.FrameworkInfo)
+	info.Id = id
+	c.frameworkInfo = info
+}
+
 // Make a subscription call to mesos.
 // Channel passed is the channel for Event Controller.
 func (c *DefaultScheduler) Subscribe(eventChan chan *sched.Event) (*http.Response, error) {
+	return c.SubscribeContext(context.Background(), eventChan)
+}
+
+// The subscription stream is closed as soon as the context is done.
+func (c *DefaultScheduler) SubscribeContext(ctx context.Context, eventChan chan *sched.Event) (*http.Response, error) {
+	info := c.FrameworkInfo()
 	call := &sched.Call{
 		Type: sched.Call_SUBSCRIBE.Enum(),
 		Subscribe: &sched.Call_Subscribe{
-			FrameworkInfo: c.frameworkInfo,
+			FrameworkInfo: info,
 		},
-		FrameworkId: c.frameworkInfo.Id,
+		FrameworkId: info.Id,
 	}
 
+	// Mesos forgets about suppression when we subscribe again.
+	c.Lock()
+	c.IsSuppressed = false
+	c.suppressed = make(map[string]bool)
+	c.Unlock()
+
 	// If we disconnect we need to reset the stream ID. For this reason always start with a fresh stream ID.
 	// Otherwise we'll never be able to reconnect.
 	c.Client.SetStreamID("")
 
-	resp, err := c.Client.Request(call)
+	resp, err := client.RequestContext(ctx, c.Client, call)
 	if err != nil {
 		return resp, err
 	} else {
 		// recordio.Decode() returns an err struct
-		return resp, recordio.Decode(resp.Body, eventChan)
+		return resp, recordio.DecodeWithCodec(ctx, recordio.CodecFor(resp.Header), resp.Body, eventChan)
 	}
 }
 
 // Send a teardown request to mesos master.
 func (c *DefaultScheduler) Teardown() (*http.Response, error) {
+	return c.TeardownContext(context.Background())
+}
+
+func (c *DefaultScheduler) TeardownContext(ctx context.Context) (*http.Response, error) {
 	teardown := &sched.Call{
-		FrameworkId: c.frameworkInfo.GetId(),
+		FrameworkId: c.FrameworkInfo().GetId(),
 		Type:        sched.Call_TEARDOWN.Enum(),
 	}
-	resp, err := c.Client.Request(teardown)
+	resp, err := client.RequestContext(ctx, c.Client, teardown)
 	if err != nil {
 		c.logger.Emit(logging.ERROR, err.Error())
 	}
@@ -117,13 +176,17 @@ func (c *DefaultScheduler) Teardown() (*http.Response, error) {
 
 // Accepts offers from mesos master
 func (c *DefaultScheduler) Accept(offerIds []*mesos_v1.OfferID, tasks []*mesos_v1.Offer_Operation, filters *mesos_v1.Filters) (*http.Response, error) {
+	return c.AcceptContext(context.Background(), offerIds, tasks, filters)
+}
+
+func (c *DefaultScheduler) AcceptContext(ctx context.Context, offerIds []*mesos_v1.OfferID, tasks []*mesos_v1.Offer_Operation, filters *mesos_v1.Filters) (*http.Response, error) {
 	accept := &sched.Call{
-		FrameworkId: c.frameworkInfo.GetId(),
+		FrameworkId: c.FrameworkInfo().GetId(),
 		Type:        sched.Call_ACCEPT.Enum(),
 		Accept:      &sched.Call_Accept{OfferIds: offerIds, Operations: tasks, Filters: filters},
 	}
 
-	resp, err := c.Client.Request(accept)
+	resp, err := client.RequestContext(ctx, c.Client, accept)
 	if err != nil {
 		c.logger.Emit(logging.ERROR, err.Error())
 		return nil, err
@@ -134,14 +197,18 @@ func (c *DefaultScheduler) Accept(offerIds []*mesos_v1.OfferID, tasks []*mesos_v
 }
 
 func (c *DefaultScheduler) Decline(offerIds []*mesos_v1.OfferID, filters *mesos_v1.Filters) (*http.Response, error) {
+	return c.DeclineContext(context.Background(), offerIds, filters)
+}
+
+func (c *DefaultScheduler) DeclineContext(ctx context.Context, offerIds []*mesos_v1.OfferID, filters *mesos_v1.Filters) (*http.Response, error) {
 	// Get a list of the offer ids to decline and any filters.
 	decline := &sched.Call{
-		FrameworkId: c.frameworkInfo.GetId(),
+		FrameworkId: c.FrameworkInfo().GetId(),
 		Type:        sched.Call_DECLINE.Enum(),
 		Decline:     &sched.Call_Decline{OfferIds: offerIds, Filters: filters},
 	}
 
-	resp, err := c.Client.Request(decline)
+	resp, err := client.RequestContext(ctx, c.Client, decline)
 	if err != nil {
 		c.logger.Emit(logging.ERROR, err.Error())
 	}
@@ -155,25 +222,31 @@ func (c *DefaultScheduler) Decline(offerIds []*mesos_v1.OfferID, filters *mesos_
 }
 
 // Sent by the scheduler to remove any/all filters that it has previously set via ACCEPT or DECLINE calls.
+// Nothing is sent unless offers were suppressed, either for everything or for some of our roles.
 func (c *DefaultScheduler) Revive() (*http.Response, error) {
+	return c.ReviveContext(context.Background())
+}
+
+func (c *DefaultScheduler) ReviveContext(ctx context.Context) (*http.Response, error) {
 	c.RLock()
-	if !c.IsSuppressed {
+	if !c.IsSuppressed && len(c.suppressed) == 0 {
 		c.RUnlock()
 		return nil, nil
 	}
 	c.RUnlock()
 
 	revive := &sched.Call{
-		FrameworkId: c.frameworkInfo.GetId(),
+		FrameworkId: c.FrameworkInfo().GetId(),
 		Type:        sched.Call_REVIVE.Enum(),
 	}
 
-	resp, err := c.Client.Request(revive)
+	resp, err := client.RequestContext(ctx, c.Client, revive)
 	if err != nil {
 		c.logger.Emit(logging.ERROR, err.Error())
 	} else {
 		c.Lock()
 		c.IsSuppressed = false
+		c.suppressed = make(map[string]bool)
 		c.Unlock()
 
 		c.logger.Emit(logging.INFO, "Reviving offers")
@@ -183,41 +256,63 @@ func (c *DefaultScheduler) Revive() (*http.Response, error) {
 }
 
 func (c *DefaultScheduler) Kill(taskId *mesos_v1.TaskID, agentid *mesos_v1.AgentID) (*http.Response, error) {
+	return c.KillContext(context.Background(), taskId, agentid)
+}
+
+func (c *DefaultScheduler) KillContext(ctx context.Context, taskId *mesos_v1.TaskID, agentid *mesos_v1.AgentID) (*http.Response, error) {
+	return c.KillWithPolicyContext(ctx, taskId, agentid, nil)
+}
+
+// Kills a task, overriding the grace period it was launched with.
+func (c *DefaultScheduler) KillWithPolicy(taskId *mesos_v1.TaskID, agentid *mesos_v1.AgentID, policy *mesos_v1.KillPolicy) (*http.Response, error) {
+	return c.KillWithPolicyContext(context.Background(), taskId, agentid, policy)
+}
+
+func (c *DefaultScheduler) KillWithPolicyContext(ctx context.Context, taskId *mesos_v1.TaskID, agentid *mesos_v1.AgentID, policy *mesos_v1.KillPolicy) (*http.Response, error) {
 	kill := &sched.Call{
-		FrameworkId: c.frameworkInfo.GetId(),
+		FrameworkId: c.FrameworkInfo().GetId(),
 		Type:        sched.Call_KILL.Enum(),
-		Kill:        &sched.Call_Kill{TaskId: taskId, AgentId: agentid},
+		Kill:        &sched.Call_Kill{TaskId: taskId, AgentId: agentid, KillPolicy: policy},
 	}
 
-	resp, err := c.Client.Request(kill)
+	resp, err := client.RequestContext(ctx, c.Client, kill)
 	if err != nil {
 		c.logger.Emit(logging.ERROR, err.Error())
 	}
 	// Kill returns a 202 accepted.
-	if resp.StatusCode == 202 {
+	if resp != nil && resp.StatusCode == 202 {
 		c.logger.Emit(logging.INFO, "Killing task %s", taskId.GetValue())
 	}
 	return resp, err
 }
 
 func (c *DefaultScheduler) Shutdown(execId *mesos_v1.ExecutorID, agentId *mesos_v1.AgentID) (*http.Response, error) {
+	return c.ShutdownContext(context.Background(), execId, agentId)
+}
+
+func (c *DefaultScheduler) ShutdownContext(ctx context.Context, execId *mesos_v1.ExecutorID, agentId *mesos_v1.AgentID) (*http.Response, error) {
 	shutdown := &sched.Call{
-		FrameworkId: c.frameworkInfo.GetId(),
+		FrameworkId: c.FrameworkInfo().GetId(),
 		Type:        sched.Call_SHUTDOWN.Enum(),
 		Shutdown: &sched.Call_Shutdown{
 			ExecutorId: execId,
 			AgentId:    agentId,
 		},
 	}
-	resp, err := c.Client.Request(shutdown)
+	resp, err := client.RequestContext(ctx, c.Client, shutdown)
 	if err != nil {
 		c.logger.Emit(logging.ERROR, err.Error())
+	} else {
+		c.logger.Emit(logging.INFO, "Shutting down executor %s", execId.GetValue())
 	}
-	c.logger.Emit(logging.INFO, "Shutting down")
 	return resp, err
 }
 
 func (c *DefaultScheduler) Acknowledge(agentId *mesos_v1.AgentID, taskId *mesos_v1.TaskID, uuid []byte) (*http.Response, error) {
+	return c.AcknowledgeContext(context.Background(), agentId, taskId, uuid)
+}
+
+func (c *DefaultScheduler) AcknowledgeContext(ctx context.Context, agentId *mesos_v1.AgentID, taskId *mesos_v1.TaskID, uuid []byte) (*http.Response, error) {
 
 	// Note that with the new API, schedulers are responsible for explicitly acknowledging the receipt of status
 	// updates that have “status.uuid()” set.
@@ -228,7 +323,7 @@ func (c *DefaultScheduler) Acknowledge(agentId *mesos_v1.AgentID, taskId *mesos_
 	}
 
 	acknowledge := &sched.Call{
-		FrameworkId: c.frameworkInfo.GetId(),
+		FrameworkId: c.FrameworkInfo().GetId(),
 		Type:        sched.Call_ACKNOWLEDGE.Enum(),
 		Acknowledge: &sched.Call_Acknowledge{
 			AgentId: agentId,
@@ -237,7 +332,7 @@ func (c *DefaultScheduler) Acknowledge(agentId *mesos_v1.AgentID, taskId *mesos_
 		},
 	}
 
-	resp, err := c.Client.Request(acknowledge)
+	resp, err := client.RequestContext(ctx, c.Client, acknowledge)
 	if err != nil {
 		c.logger.Emit(logging.ERROR, err.Error())
 	}
@@ -245,6 +340,10 @@ func (c *DefaultScheduler) Acknowledge(agentId *mesos_v1.AgentID, taskId *mesos_
 }
 
 func (c *DefaultScheduler) Reconcile(tasks []*mesos_v1.TaskInfo) (*http.Response, error) {
+	return c.ReconcileContext(context.Background(), tasks)
+}
+
+func (c *DefaultScheduler) ReconcileContext(ctx context.Context, tasks []*mesos_v1.TaskInfo) (*http.Response, error) {
 	reconcileTasks := make([]*sched.Call_Reconcile_Task, 0, len(tasks))
 	for _, task := range tasks {
 		reconcileTasks = append(reconcileTasks, &sched.Call_Reconcile_Task{
@@ -254,13 +353,13 @@ func (c *DefaultScheduler) Reconcile(tasks []*mesos_v1.TaskInfo) (*http.Response
 	}
 
 	reconcile := &sched.Call{
-		FrameworkId: c.frameworkInfo.GetId(),
+		FrameworkId: c.FrameworkInfo().GetId(),
 		Type:        sched.Call_RECONCILE.Enum(),
 		Reconcile: &sched.Call_Reconcile{
 			Tasks: reconcileTasks,
 		},
 	}
-	resp, err := c.Client.Request(reconcile)
+	resp, err := client.RequestContext(ctx, c.Client, reconcile)
 	if err != nil {
 		c.logger.Emit(logging.ERROR, err.Error())
 	}
@@ -270,8 +369,12 @@ func (c *DefaultScheduler) Reconcile(tasks []*mesos_v1.TaskInfo) (*http.Response
 }
 
 func (c *DefaultScheduler) Message(agentId *mesos_v1.AgentID, executorId *mesos_v1.ExecutorID, data []byte) (*http.Response, error) {
+	return c.MessageContext(context.Background(), agentId, executorId, data)
+}
+
+func (c *DefaultScheduler) MessageContext(ctx context.Context, agentId *mesos_v1.AgentID, executorId *mesos_v1.ExecutorID, data []byte) (*http.Response, error) {
 	message := &sched.Call{
-		FrameworkId: c.frameworkInfo.GetId(),
+		FrameworkId: c.FrameworkInfo().GetId(),
 		Type:        sched.Call_MESSAGE.Enum(),
 		Message: &sched.Call_Message{
 			AgentId:    agentId,
@@ -279,7 +382,7 @@ func (c *DefaultScheduler) Message(agentId *mesos_v1.AgentID, executorId *mesos_
 			Data:       data,
 		},
 	}
-	resp, err := c.Client.Request(message)
+	resp, err := client.RequestContext(ctx, c.Client, message)
 	if err != nil {
 		c.logger.Emit(logging.ERROR, err.Error())
 	}
@@ -289,14 +392,18 @@ func (c *DefaultScheduler) Message(agentId *mesos_v1.AgentID, executorId *mesos_
 
 // NOTE: This method is only kept to conform to official Mesos codebase.  This does nothing.
 func (c *DefaultScheduler) SchedRequest(resources []*mesos_v1.Request) (*http.Response, error) {
+	return c.SchedRequestContext(context.Background(), resources)
+}
+
+func (c *DefaultScheduler) SchedRequestContext(ctx context.Context, resources []*mesos_v1.Request) (*http.Response, error) {
 	request := &sched.Call{
-		FrameworkId: c.frameworkInfo.GetId(),
+		FrameworkId: c.FrameworkInfo().GetId(),
 		Type:        sched.Call_REQUEST.Enum(),
 		Request: &sched.Call_Request{
 			Requests: resources,
 		},
 	}
-	resp, err := c.Client.Request(request)
+	resp, err := client.RequestContext(ctx, c.Client, request)
 	if err != nil {
 		c.logger.Emit(logging.ERROR, err.Error())
 	}
@@ -306,6 +413,10 @@ func (c *DefaultScheduler) SchedRequest(resources []*mesos_v1.Request) (*http.Re
 
 // Makes a call to Mesos to suppress any further offers.
 func (c *DefaultScheduler) Suppress() (*http.Response, error) {
+	return c.SuppressContext(context.Background())
+}
+
+func (c *DefaultScheduler) SuppressContext(ctx context.Context) (*http.Response, error) {
 	c.RLock()
 	if c.IsSuppressed {
 		c.RUnlock()
@@ -314,10 +425,10 @@ func (c *DefaultScheduler) Suppress() (*http.Response, error) {
 	c.RUnlock()
 
 	suppress := &sched.Call{
-		FrameworkId: c.frameworkInfo.GetId(),
+		FrameworkId: c.FrameworkInfo().GetId(),
 		Type:        sched.Call_SUPPRESS.Enum(),
 	}
-	resp, err := c.Client.Request(suppress)
+	resp, err := client.RequestContext(ctx, c.Client, suppress)
 	if err != nil {
 		c.logger.Emit(logging.ERROR, err.Error())
 	} else {
@@ -330,3 +441,114 @@ func (c *DefaultScheduler) Suppress() (*http.Response, error) {
 
 	return resp, err
 }
+
+// Suppresses offers for the given roles only, or for all of them if none are given.
+// Unlike Suppress this always makes the call, suppressed roles are only remembered so that Revive knows to undo them.
+func (c *DefaultScheduler) SuppressRoles(roles []string) (*http.Response, error) {
+	return c.SuppressRolesContext(context.Background(), roles)
+}
+
+func (c *DefaultScheduler) SuppressRolesContext(ctx context.Context, roles []string) (*http.Response, error) {
+	suppress := &sched.Call{
+		FrameworkId: c.FrameworkInfo().GetId(),
+		Type:        sched.Call_SUPPRESS.Enum(),
+		Suppress:    &sched.Call_Suppress{Roles: roles},
+	}
+	resp, err := client.RequestContext(ctx, c.Client, suppress)
+	if err != nil {
+		c.logger.Emit(logging.ERROR, err.Error())
+		return resp, err
+	}
+
+	c.Lock()
+	if len(roles) == 0 {
+		c.IsSuppressed = true
+	}
+	if c.suppressed == nil {
+		c.suppressed = make(map[string]bool)
+	}
+	for _, role := range roles {
+		c.suppressed[role] = true
+	}
+	c.Unlock()
+	c.logger.Emit(logging.INFO, "Suppressing offers for roles %v", roles)
+
+	return resp, err
+}
+
+// Revives offers for the given roles only, or for all of them if none are given.
+func (c *DefaultScheduler) ReviveRoles(roles []string) (*http.Response, error) {
+	return c.ReviveRolesContext(context.Background(), roles)
+}
+
+func (c *DefaultScheduler) ReviveRolesContext(ctx context.Context, roles []string) (*http.Response, error) {
+	revive := &sched.Call{
+		FrameworkId: c.FrameworkInfo().GetId(),
+		Type:        sched.Call_REVIVE.Enum(),
+		Revive:      &sched.Call_Revive{Roles: roles},
+	}
+	resp, err := client.RequestContext(ctx, c.Client, revive)
+	if err != nil {
+		c.logger.Emit(logging.ERROR, err.Error())
+		return resp, err
+	}
+
+	c.Lock()
+	if len(roles) == 0 {
+		c.IsSuppressed = false
+		c.suppressed = make(map[string]bool)
+	}
+	for _, role := range roles {
+		delete(c.suppressed, role)
+	}
+	c.Unlock()
+	c.logger.Emit(logging.INFO, "Reviving offers for roles %v", roles)
+
+	return resp, err
+}
+
+// Lets Mesos know that we're fine with the unavailability described by the inverse offers.
+// This should only be done once our tasks on the affected agents have been drained.
+func (c *DefaultScheduler) AcceptInverseOffers(offerIds []*mesos_v1.OfferID, filters *mesos_v1.Filters) (*http.Response, error) {
+	return c.AcceptInverseOffersContext(context.Background(), offerIds, filters)
+}
+
+func (c *DefaultScheduler) AcceptInverseOffersContext(ctx context.Context, offerIds []*mesos_v1.OfferID, filters *mesos_v1.Filters) (*http.Response, error) {
+	accept := &sched.Call{
+		FrameworkId:         c.FrameworkInfo().GetId(),
+		Type:                sched.Call_ACCEPT_INVERSE_OFFERS.Enum(),
+		AcceptInverseOffers: &sched.Call_AcceptInverseOffers{InverseOfferIds: offerIds, Filters: filters},
+	}
+
+	resp, err := client.RequestContext(ctx, c.Client, accept)
+	if err != nil {
+		c.logger.Emit(logging.ERROR, err.Error())
+		return resp, err
+	}
+
+	c.logger.Emit(logging.INFO, "Accepting %d inverse offers", len(offerIds))
+	return resp, err
+}
+
+// Lets Mesos know that the unavailability described by the inverse offers would hurt us.
+// This is only a hint to the operator, the maintenance can still go ahead.
+func (c *DefaultScheduler) DeclineInverseOffers(offerIds []*mesos_v1.OfferID, filters *mesos_v1.Filters) (*http.Response, error) {
+	return c.DeclineInverseOffersContext(context.Background(), offerIds, filters)
+}
+
+func (c *DefaultScheduler) DeclineInverseOffersContext(ctx context.Context, offerIds []*mesos_v1.OfferID, filters *mesos_v1.Filters) (*http.Response, error) {
+	decline := &sched.Call{
+		FrameworkId:          c.FrameworkInfo().GetId(),
+		Type:                 sched.Call_DECLINE_INVERSE_OFFERS.Enum(),
+		DeclineInverseOffers: &sched.Call_DeclineInverseOffers{InverseOfferIds: offerIds, Filters: filters},
+	}
+
+	resp, err := client.RequestContext(ctx, c.Client, decline)
+	if err != nil {
+		c.logger.Emit(logging.ERROR, err.Error())
+		return resp, err
+	}
+
+	c.logger.Emit(logging.INFO, "Declining %d inverse offers", len(offerIds))
+	return resp, err
+}
@@ -15,13 +15,18 @@
 package scheduler
 
 import (
-	"io"
+	"context"
+	"github.com/golang/protobuf/proto"
 	"github.com/verizonlabs/mesos-framework-sdk/client"
 	"github.com/verizonlabs/mesos-framework-sdk/include/mesos_v1"
 	"github.com/verizonlabs/mesos-framework-sdk/include/mesos_v1_scheduler"
+	"github.com/verizonlabs/mesos-framework-sdk/recordio"
+	"io"
 	"net/http"
 	"net/http/httptest"
+	"strconv"
 	"testing"
+	"time"
 )
 
 type mockClient struct{}
@@ -30,6 +35,10 @@ func (m *mockClient) Request(interface{}) (*http.Response, error) {
 	return new(http.Response), nil
 }
 
+func (m *mockClient) RequestContext(context.Context, interface{}) (*http.Response, error) {
+	return new(http.Response), nil
+}
+
 func (m *mockClient) StreamID() string {
 	return "test"
 }
@@ -60,6 +69,36 @@ func TestNewDefaultScheduler(t *testing.T) {
 	}
 }
 
+// Makes sure the framework ID can be changed while calls are being made, without touching the caller's framework info.
+func TestDefaultScheduler_SetFrameworkId(t *testing.T) {
+	t.Parallel()
+
+	info := &mesos_v1.FrameworkInfo{}
+	r := new(recordingClient)
+	s := NewDefaultScheduler(r, info, l)
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for n := 0; n < 100; n++ {
+			s.Reconcile(nil)
+		}
+	}()
+
+	s.SetFrameworkId(&mesos_v1.FrameworkID{Value: proto.String("framework")})
+	<-done
+
+	if s.FrameworkInfo().GetId().GetValue() != "framework" || info.Id != nil {
+		t.Fatal("Framework ID should have been changed on a copy of the framework info")
+	}
+
+	s.Reconcile(nil)
+	calls := r.byType(mesos_v1_scheduler.Call_RECONCILE)
+	if calls[len(calls)-1].GetFrameworkId().GetValue() != "framework" {
+		t.Fatal("Calls should be made with the new framework ID")
+	}
+}
+
 // Measures performance of creating a new scheduler.
 func BenchmarkNewDefaultScheduler(b *testing.B) {
 	for n := 0; n < b.N; n++ {
@@ -356,6 +395,67 @@ func TestDefaultScheduler_Subscribe(t *testing.T) {
 	}
 }
 
+// Makes sure a cancelled context tears down a subscription that's blocked waiting on events.
+func TestDefaultScheduler_SubscribeContext(t *testing.T) {
+	t.Parallel()
+
+	ch := make(chan *mesos_v1_scheduler.Event)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+		w.(http.Flusher).Flush()
+		<-r.Context().Done()
+	}))
+	defer srv.Close()
+	c := client.NewClient(client.ClientData{
+		Endpoint: srv.URL,
+	}, l)
+	val := "test"
+	s := NewDefaultScheduler(c, &mesos_v1.FrameworkInfo{
+		User: &val,
+		Name: &val,
+	}, l)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	time.AfterFunc(50*time.Millisecond, cancel)
+
+	_, err := s.SubscribeContext(ctx, ch)
+	if err != context.Canceled {
+		t.Fatal("Expected the subscription to be cancelled")
+	}
+}
+
+// Ensures events are decoded as JSON when that's what the master streams back.
+func TestDefaultScheduler_SubscribeJSON(t *testing.T) {
+	t.Parallel()
+
+	ch := make(chan *mesos_v1_scheduler.Event, 1)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		event := `{"type":"HEARTBEAT"}`
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(strconv.Itoa(len(event)) + "\n" + event))
+	}))
+	defer srv.Close()
+	c := client.NewClient(client.ClientData{
+		Endpoint: srv.URL,
+		Codec:    recordio.JSON,
+	}, l)
+	val := "test"
+	s := NewDefaultScheduler(c, &mesos_v1.FrameworkInfo{
+		User: &val,
+		Name: &val,
+	}, l)
+
+	_, err := s.Subscribe(ch)
+	if err != io.EOF {
+		t.Fatal("Expected EOF but encountered another error: " + err.Error())
+	}
+
+	event := <-ch
+	if event.GetType() != mesos_v1_scheduler.Event_HEARTBEAT {
+		t.Fatal("JSON event was not decoded correctly")
+	}
+}
+
 // Measures performance of our subscribe call to Mesos.
 func BenchmarkDefaultScheduler_Subscribe(b *testing.B) {
 	ch := make(chan *mesos_v1_scheduler.Event)
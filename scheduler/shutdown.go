@@ -0,0 +1,209 @@
+// Copyright 2017 Verizon
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package scheduler
+
+import (
+	"context"
+	"github.com/verizonlabs/mesos-framework-sdk/include/mesos_v1"
+	"github.com/verizonlabs/mesos-framework-sdk/logging"
+	"github.com/verizonlabs/mesos-framework-sdk/task/manager"
+	"sort"
+	"sync"
+	"time"
+)
+
+/*
+GracefulShutdown winds the framework down without yanking everything out from under our tasks.
+
+Offers are suppressed first and Stopping tells the offer handler to decline anything still coming in.
+Tasks are then killed in waves, ordered by the config, and each wave waits until the task manager shows all of its
+tasks as terminated before moving on. This relies on status updates being recorded in the task manager as usual.
+Once everything is down we either tear down the framework or just disconnect, leaving the framework registered so
+that another scheduler instance can fail over to it.
+*/
+type GracefulShutdown struct {
+	scheduler Scheduler
+	tasks     manager.TaskManager
+	config    ShutdownConfig
+	logger    logging.Logger
+	stopping  bool
+	lock      sync.Mutex
+}
+
+// Controls how Shutdown drains our tasks.
+// By default the task manager is polled every 500ms, every task is killed in one wave with the grace period it was
+// launched with, and the framework stays registered.
+type ShutdownConfig struct {
+	GracePeriod  time.Duration // Time tasks get to exit before they're forcibly killed, zero keeps the grace period they were launched with.
+	PollInterval time.Duration // How often the task manager is checked while waiting for tasks to terminate.
+	Teardown     bool          // Tear down the framework once drained instead of leaving it registered for failover.
+
+	// Tells us which wave a task is killed in, lower waves go first.
+	// All tasks are killed at once by default.
+	Order func(*manager.Task) int
+
+	// Called once we're done to drop our subscription, such as cancelling the context it runs with.
+	Disconnect func()
+}
+
+func NewGracefulShutdown(s Scheduler, tasks manager.TaskManager, config ShutdownConfig, logger logging.Logger) *GracefulShutdown {
+	if config.PollInterval <= 0 {
+		config.PollInterval = 500 * time.Millisecond
+	}
+	if config.Order == nil {
+		config.Order = func(*manager.Task) int {
+			return 0
+		}
+	}
+
+	return &GracefulShutdown{
+		scheduler: s,
+		tasks:     tasks,
+		config:    config,
+		logger:    logger,
+	}
+}
+
+// Tells us if we're shutting down, in which case offers should be declined instead of used.
+func (g *GracefulShutdown) Stopping() bool {
+	g.lock.Lock()
+	defer g.lock.Unlock()
+
+	return g.stopping
+}
+
+// Kills all of our tasks and then tears down or disconnects.
+// If the context is done before all tasks have terminated we stop waiting and return its error,
+// leaving the framework registered.
+func (g *GracefulShutdown) Shutdown(ctx context.Context) error {
+	g.lock.Lock()
+	g.stopping = true
+	g.lock.Unlock()
+
+	g.logger.Emit(logging.INFO, "Shutting down gracefully")
+
+	resp, err := g.scheduler.SuppressRolesContext(ctx, nil)
+	closeBody(resp)
+	if err != nil {
+		g.logger.Emit(logging.ERROR, "Failed to suppress offers while shutting down: %s", err.Error())
+	}
+
+	waves, err := g.waves()
+	if err != nil {
+		return err
+	}
+
+	for _, wave := range waves {
+		g.kill(ctx, wave)
+		if err := g.wait(ctx, wave); err != nil {
+			g.logger.Emit(logging.ERROR, "Gave up waiting for tasks to terminate: %s", err.Error())
+			return err
+		}
+	}
+
+	if g.config.Teardown {
+		resp, err := g.scheduler.TeardownContext(ctx)
+		closeBody(resp)
+		if err != nil {
+			return err
+		}
+	}
+
+	if g.config.Disconnect != nil {
+		g.config.Disconnect()
+	}
+	g.logger.Emit(logging.INFO, "All tasks have terminated, shutdown complete")
+
+	return nil
+}
+
+// Groups the tasks that are still running into the waves they'll be killed in.
+func (g *GracefulShutdown) waves() ([][]*manager.Task, error) {
+	tasks, err := g.tasks.All()
+	if err != nil {
+		g.logger.Emit(logging.ERROR, "Failed to get tasks to shut down: %s", err.Error())
+		return nil, err
+	}
+
+	byOrder := make(map[int][]*manager.Task)
+	var orders []int
+	for _, t := range tasks {
+
+		// Tasks that were never launched have nothing to kill.
+		if t.Info == nil || t.Info.AgentId == nil || isTerminal(t.State) {
+			continue
+		}
+
+		order := g.config.Order(t)
+		if _, ok := byOrder[order]; !ok {
+			orders = append(orders, order)
+		}
+		byOrder[order] = append(byOrder[order], t)
+	}
+	sort.Ints(orders)
+
+	waves := make([][]*manager.Task, 0, len(orders))
+	for _, order := range orders {
+		waves = append(waves, byOrder[order])
+	}
+
+	return waves, nil
+}
+
+func (g *GracefulShutdown) kill(ctx context.Context, wave []*manager.Task) {
+	var policy *mesos_v1.KillPolicy
+	if g.config.GracePeriod > 0 {
+		nanoseconds := g.config.GracePeriod.Nanoseconds()
+		policy = &mesos_v1.KillPolicy{
+			GracePeriod: &mesos_v1.DurationInfo{Nanoseconds: &nanoseconds},
+		}
+	}
+
+	for _, t := range wave {
+		resp, err := g.scheduler.KillWithPolicyContext(ctx, t.Info.GetTaskId(), t.Info.GetAgentId(), policy)
+		closeBody(resp)
+		if err != nil {
+			g.logger.Emit(logging.ERROR, "Failed to kill task %s: %s", t.Info.GetTaskId().GetValue(), err.Error())
+		}
+	}
+}
+
+// Waits until the task manager shows every task in the wave as terminated or gone.
+func (g *GracefulShutdown) wait(ctx context.Context, wave []*manager.Task) error {
+	ticker := time.NewTicker(g.config.PollInterval)
+	defer ticker.Stop()
+
+	for {
+		remaining := wave[:0]
+		for _, t := range wave {
+			current, err := g.tasks.GetById(t.Info.GetTaskId())
+			if err != nil || isTerminal(current.State) {
+				continue
+			}
+			remaining = append(remaining, t)
+		}
+
+		wave = remaining
+		if len(wave) == 0 {
+			return nil
+		}
+
+		select {
+		case <-ticker.C:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
@@ -0,0 +1,124 @@
+// Copyright 2017 Verizon
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package scheduler
+
+import (
+	"context"
+	"errors"
+	"github.com/verizonlabs/mesos-framework-sdk/include/mesos_v1"
+	"github.com/verizonlabs/mesos-framework-sdk/include/mesos_v1_scheduler"
+	"github.com/verizonlabs/mesos-framework-sdk/task/manager"
+	"testing"
+	"time"
+)
+
+// Looks up tasks so that the shutdown can check on them while waiting.
+func (m *mockTaskManager) GetById(id *mesos_v1.TaskID) (*manager.Task, error) {
+	for _, t := range m.tasks {
+		if t.Info.GetTaskId().GetValue() == id.GetValue() {
+			return t, nil
+		}
+	}
+
+	return nil, errors.New("Task not found")
+}
+
+// Kills tasks right away, like a master whose status updates are recorded in the task manager.
+func killing(tasks *mockTaskManager) func(*mesos_v1_scheduler.Call) error {
+	return func(call *mesos_v1_scheduler.Call) error {
+		if kill := call.GetKill(); kill != nil {
+			t, _ := tasks.GetById(kill.GetTaskId())
+			t.State = manager.KILLED
+		}
+
+		return nil
+	}
+}
+
+// Makes sure tasks are killed in order with our grace period before tearing down.
+func TestGracefulShutdown_Shutdown(t *testing.T) {
+	t.Parallel()
+
+	tasks := &mockTaskManager{
+		tasks: []*manager.Task{
+			mockTask("db", "agent", manager.RUNNING),
+			mockTask("web", "agent", manager.RUNNING),
+			mockTask("finished", "agent", manager.FINISHED),
+		},
+	}
+	c := &recordingClient{onCall: killing(tasks)}
+
+	disconnected := false
+	g := NewGracefulShutdown(NewDefaultScheduler(c, i, l), tasks, ShutdownConfig{
+		GracePeriod:  time.Second,
+		PollInterval: time.Millisecond,
+		Teardown:     true,
+		Order: func(t *manager.Task) int {
+			if t.Info.GetTaskId().GetValue() == "db" {
+				return 1
+			}
+			return 0
+		},
+		Disconnect: func() {
+			disconnected = true
+		},
+	}, l)
+
+	if g.Stopping() {
+		t.Fatal("Should not be stopping before shutdown")
+	}
+
+	if err := g.Shutdown(context.Background()); err != nil {
+		t.Fatal(err.Error())
+	}
+
+	kills := c.byType(mesos_v1_scheduler.Call_KILL)
+	if len(kills) != 2 || kills[0].Kill.GetTaskId().GetValue() != "web" || kills[1].Kill.GetTaskId().GetValue() != "db" {
+		t.Fatal("Tasks were not killed in order")
+	}
+
+	if kills[0].Kill.GetKillPolicy().GetGracePeriod().GetNanoseconds() != int64(time.Second) {
+		t.Fatal("Kill policy was not set")
+	}
+
+	if !g.Stopping() || len(c.byType(mesos_v1_scheduler.Call_SUPPRESS)) != 1 || len(c.byType(mesos_v1_scheduler.Call_TEARDOWN)) != 1 || !disconnected {
+		t.Fatal("Offers should have been suppressed and the framework torn down")
+	}
+}
+
+// Ensures we stop waiting on tasks that never terminate and leave the framework registered.
+func TestGracefulShutdown_ShutdownTimeout(t *testing.T) {
+	t.Parallel()
+
+	tasks := &mockTaskManager{
+		tasks: []*manager.Task{mockTask("stubborn", "agent", manager.RUNNING)},
+	}
+	r := new(recordingClient)
+	g := NewGracefulShutdown(NewDefaultScheduler(r, i, l), tasks, ShutdownConfig{
+		PollInterval: time.Millisecond,
+		Teardown:     true,
+	}, l)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+
+	if err := g.Shutdown(ctx); err != context.DeadlineExceeded {
+		t.Fatal("Shutdown should have given up once the context expired")
+	}
+
+	if len(r.byType(mesos_v1_scheduler.Call_KILL)) != 1 || len(r.byType(mesos_v1_scheduler.Call_TEARDOWN)) != 0 {
+		t.Fatal("Framework should not be torn down while tasks are still running")
+	}
+}
@@ -4,4 +4,14 @@ const (
 	COLOCATE string = "mux"
 	NONE     string = "non-mux"
 	UNIQUE   string = "unique"
+
+	FIRST_FIT string = "first-fit"
+	BIN_PACK  string = "bin-pack"
+	SPREAD    string = "spread"
+)
+
+// Efforts tell us what to do when a strategy can't be satisfied.
+const (
+	STRICT      string = "strict"
+	BEST_EFFORT string = "best-effort"
 )
@@ -0,0 +1,266 @@
+// Copyright 2017 Verizon
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package scheduler
+
+import (
+	"context"
+	"errors"
+	"github.com/golang/protobuf/proto"
+	"github.com/verizonlabs/mesos-framework-sdk/include/mesos_v1"
+	sched "github.com/verizonlabs/mesos-framework-sdk/include/mesos_v1_scheduler"
+	"github.com/verizonlabs/mesos-framework-sdk/logging"
+	"time"
+)
+
+/*
+SubscriptionSupervisor keeps the scheduler subscribed to Mesos.
+
+Subscribe only returns once the event stream breaks, which never happens if the connection is left half-open.
+The supervisor watches for heartbeats and tears down any subscription that goes quiet for too long.
+It then subscribes again using the framework ID handed to us by Mesos, so our tasks survive as long as we're back
+within the failover timeout set in the framework info.
+*/
+type SubscriptionSupervisor struct {
+	scheduler Scheduler
+	events    chan *sched.Event
+	config    SupervisorConfig
+	logger    logging.Logger
+	state     ConnectionState
+}
+
+// Heartbeat and resubscription timing.
+// We give up on a connection after 3 missed heartbeats and resubscribe with a delay starting at 1 second,
+// capped at 30 seconds. Our framework ID is only kept in memory unless a store is given.
+type SupervisorConfig struct {
+	MissedHeartbeats int           // Number of heartbeats that can be missed before the connection is considered stale.
+	InitialBackoff   time.Duration // Delay before the first resubscription attempt.
+	MaxBackoff       time.Duration // Delays double after each failure up to this limit.
+
+	// Called whenever the state of our subscription changes.
+	// The error describes why we disconnected, if we did.
+	OnStateChange func(from, to ConnectionState, err error)
+
+	// Persists our framework ID so that we keep our tasks across restarts, if set.
+	FrameworkIds *FrameworkIdStore
+}
+
+type ConnectionState uint8
+
+const (
+	DISCONNECTED ConnectionState = iota
+	CONNECTING
+	SUBSCRIBED
+)
+
+// Mesos sends heartbeats this often unless it tells us otherwise.
+const defaultHeartbeatInterval = 15 * time.Second
+
+func (s ConnectionState) String() string {
+	switch s {
+	case DISCONNECTED:
+		return "DISCONNECTED"
+	case CONNECTING:
+		return "CONNECTING"
+	case SUBSCRIBED:
+		return "SUBSCRIBED"
+	}
+
+	return "UNKNOWN"
+}
+
+// Creates a supervisor that forwards all events received from Mesos to the given channel.
+func NewSubscriptionSupervisor(s Scheduler, events chan *sched.Event, config SupervisorConfig, logger logging.Logger) *SubscriptionSupervisor {
+	if config.MissedHeartbeats <= 0 {
+		config.MissedHeartbeats = 3
+	}
+	if config.InitialBackoff <= 0 {
+		config.InitialBackoff = time.Second
+	}
+	if config.MaxBackoff <= 0 {
+		config.MaxBackoff = 30 * time.Second
+	}
+	if config.MaxBackoff < config.InitialBackoff {
+		config.MaxBackoff = config.InitialBackoff
+	}
+
+	return &SubscriptionSupervisor{
+		scheduler: s,
+		events:    events,
+		config:    config,
+		logger:    logger,
+	}
+}
+
+// Subscribes to Mesos and keeps doing so until the context is done, which is the only time this returns.
+func (s *SubscriptionSupervisor) Run(ctx context.Context) error {
+	backoff := s.config.InitialBackoff
+
+	if s.config.FrameworkIds != nil {
+		info := proto.Clone(s.scheduler.FrameworkInfo()).(*mesos_v1.FrameworkInfo)
+		if err := s.config.FrameworkIds.Load(info); err != nil {
+			s.logger.Emit(logging.ERROR, "Failed to load the stored framework ID: %s", err.Error())
+		} else if info.Id != nil {
+			s.scheduler.SetFrameworkId(info.Id)
+		}
+	}
+
+	for {
+		s.transition(CONNECTING, nil)
+
+		subscribed, err := s.subscribe(ctx)
+		if ctx.Err() != nil {
+			s.transition(DISCONNECTED, ctx.Err())
+			return ctx.Err()
+		}
+
+		s.transition(DISCONNECTED, err)
+
+		if subscribed {
+			backoff = s.config.InitialBackoff
+		}
+
+		s.logger.Emit(logging.INFO, "Resubscribing in %v", backoff)
+
+		select {
+		case <-time.After(backoff):
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+
+		backoff *= 2
+		if backoff > s.config.MaxBackoff {
+			backoff = s.config.MaxBackoff
+		}
+	}
+}
+
+// Runs a single subscription until it breaks or stops sending heartbeats.
+// Tells us if Mesos accepted the subscription at some point.
+func (s *SubscriptionSupervisor) subscribe(ctx context.Context) (bool, error) {
+	subCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	events := make(chan *sched.Event)
+	done := make(chan error, 1)
+	go func() {
+		resp, err := s.scheduler.SubscribeContext(subCtx, events)
+		closeBody(resp)
+		done <- err
+	}()
+
+	subscribed := false
+	timeout := time.Duration(s.config.MissedHeartbeats) * defaultHeartbeatInterval
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+
+	for {
+		select {
+		case event := <-events:
+			switch event.GetType() {
+			case sched.Event_SUBSCRIBED:
+				info := event.GetSubscribed()
+
+				// Hold on to our ID so that resubscribing picks up where we left off.
+				s.scheduler.SetFrameworkId(info.GetFrameworkId())
+				s.saveFrameworkId(info.GetFrameworkId())
+				if interval := info.GetHeartbeatIntervalSeconds(); interval > 0 {
+					timeout = time.Duration(float64(s.config.MissedHeartbeats) * interval * float64(time.Second))
+				}
+
+				subscribed = true
+				s.transition(SUBSCRIBED, nil)
+			case sched.Event_HEARTBEAT:
+				if s.config.FrameworkIds != nil {
+					if err := s.config.FrameworkIds.Heartbeat(); err != nil {
+						s.logger.Emit(logging.ERROR, "Failed to refresh the stored framework ID: %s", err.Error())
+					}
+				}
+			case sched.Event_ERROR:
+				if IsFrameworkRemoved(event.GetError().GetMessage()) {
+					s.forgetFrameworkId()
+				}
+			}
+
+			select {
+			case s.events <- event:
+			case <-ctx.Done():
+				return subscribed, ctx.Err()
+			}
+
+			if !timer.Stop() {
+				<-timer.C
+			}
+			timer.Reset(timeout)
+		case <-timer.C:
+			s.logger.Emit(logging.ERROR, "No heartbeat from Mesos in %v, dropping the connection", timeout)
+			cancel()
+			<-done
+
+			return subscribed, errors.New("Missed heartbeats from Mesos")
+		case err := <-done:
+			if err == nil {
+				err = errors.New("Subscription closed by Mesos")
+			} else if IsFrameworkRemoved(err.Error()) {
+				s.forgetFrameworkId()
+			}
+
+			return subscribed, err
+		}
+	}
+}
+
+// Stores our framework ID if we were asked to.
+func (s *SubscriptionSupervisor) saveFrameworkId(id *mesos_v1.FrameworkID) {
+	if s.config.FrameworkIds == nil {
+		return
+	}
+
+	if err := s.config.FrameworkIds.Save(id); err != nil {
+		s.logger.Emit(logging.ERROR, "Failed to store framework ID %s: %s", id.GetValue(), err.Error())
+	}
+}
+
+// Drops our framework ID once Mesos has removed the framework, so that we register as a new one next time.
+func (s *SubscriptionSupervisor) forgetFrameworkId() {
+	s.logger.Emit(logging.ERROR, "Framework %s has been removed by Mesos, registering as a new framework", s.scheduler.FrameworkInfo().GetId().GetValue())
+	s.scheduler.SetFrameworkId(nil)
+
+	if s.config.FrameworkIds == nil {
+		return
+	}
+
+	if err := s.config.FrameworkIds.Clear(); err != nil {
+		s.logger.Emit(logging.ERROR, "Failed to clear the stored framework ID: %s", err.Error())
+	}
+}
+
+// Records the new state of our subscription and lets any listener know about it.
+func (s *SubscriptionSupervisor) transition(to ConnectionState, err error) {
+	from := s.state
+	if from == to {
+		return
+	}
+	s.state = to
+
+	if err != nil {
+		s.logger.Emit(logging.INFO, "Subscription state changed from %s to %s: %s", from, to, err.Error())
+	} else {
+		s.logger.Emit(logging.INFO, "Subscription state changed from %s to %s", from, to)
+	}
+
+	if s.config.OnStateChange != nil {
+		s.config.OnStateChange(from, to, err)
+	}
+}
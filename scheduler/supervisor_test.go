@@ -0,0 +1,119 @@
+// Copyright 2017 Verizon
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package scheduler
+
+import (
+	"context"
+	"github.com/golang/protobuf/proto"
+	"github.com/verizonlabs/mesos-framework-sdk/include/mesos_v1"
+	"github.com/verizonlabs/mesos-framework-sdk/include/mesos_v1_scheduler"
+	"net/http"
+	"testing"
+	"time"
+)
+
+// Accepts the subscription and then goes quiet, like a master behind a half-open connection.
+type silentScheduler struct {
+	Scheduler
+	subscriptions chan *mesos_v1.FrameworkID
+}
+
+func newSilentScheduler(info *mesos_v1.FrameworkInfo, subscriptions int) *silentScheduler {
+	return &silentScheduler{
+		Scheduler:     NewDefaultScheduler(new(recordingClient), info, l),
+		subscriptions: make(chan *mesos_v1.FrameworkID, subscriptions),
+	}
+}
+
+func (s *silentScheduler) SubscribeContext(ctx context.Context, events chan *mesos_v1_scheduler.Event) (*http.Response, error) {
+	select {
+	case s.subscriptions <- s.FrameworkInfo().GetId():
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+
+	event := &mesos_v1_scheduler.Event{
+		Type: mesos_v1_scheduler.Event_SUBSCRIBED.Enum(),
+		Subscribed: &mesos_v1_scheduler.Event_Subscribed{
+			FrameworkId:              &mesos_v1.FrameworkID{Value: proto.String("framework")},
+			HeartbeatIntervalSeconds: proto.Float64(0.01),
+		},
+	}
+
+	select {
+	case events <- event:
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+
+	<-ctx.Done()
+	return nil, ctx.Err()
+}
+
+// Makes sure a subscription without heartbeats is torn down and resumed with our framework ID.
+func TestSubscriptionSupervisor_Run(t *testing.T) {
+	t.Parallel()
+
+	s := newSilentScheduler(&mesos_v1.FrameworkInfo{}, 2)
+	events := make(chan *mesos_v1_scheduler.Event, 2)
+
+	var states []ConnectionState
+	sup := NewSubscriptionSupervisor(s, events, SupervisorConfig{
+		MissedHeartbeats: 2,
+		InitialBackoff:   time.Millisecond,
+		OnStateChange: func(from, to ConnectionState, err error) {
+			states = append(states, to)
+		},
+	}, l)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	result := make(chan error)
+	go func() {
+		result <- sup.Run(ctx)
+	}()
+
+	if id := <-s.subscriptions; id != nil {
+		t.Fatal("First subscription should not have a framework ID")
+	}
+
+	if id := <-s.subscriptions; id.GetValue() != "framework" {
+		t.Fatal("Resubscription should have used the framework ID from Mesos")
+	}
+
+	cancel()
+	if err := <-result; err != context.Canceled {
+		t.Fatal("Supervisor should only stop once cancelled")
+	}
+
+	if (<-events).GetType() != mesos_v1_scheduler.Event_SUBSCRIBED {
+		t.Fatal("Events were not forwarded")
+	}
+
+	expected := []ConnectionState{CONNECTING, SUBSCRIBED, DISCONNECTED, CONNECTING}
+	for n, state := range expected {
+		if states[n] != state {
+			t.Fatalf("Expected state %s at transition %d, got %s", state, n, states[n])
+		}
+	}
+}
+
+// Checks that states are named properly for logging.
+func TestConnectionState_String(t *testing.T) {
+	t.Parallel()
+
+	if SUBSCRIBED.String() != "SUBSCRIBED" || ConnectionState(10).String() != "UNKNOWN" {
+		t.Fatal("Connection states are named incorrectly")
+	}
+}
@@ -15,6 +15,7 @@
 package test
 
 import (
+	"context"
 	"errors"
 	"github.com/verizonlabs/mesos-framework-sdk/include/mesos_v1"
 	"github.com/verizonlabs/mesos-framework-sdk/include/mesos_v1_scheduler"
@@ -35,6 +36,10 @@ func (m MockScheduler) FrameworkInfo() *mesos_v1.FrameworkInfo {
 	return &mesos_v1.FrameworkInfo{}
 }
 
+func (m MockScheduler) SetFrameworkId(id *mesos_v1.FrameworkID) {
+
+}
+
 func (m MockScheduler) Subscribe(chan *mesos_v1_scheduler.Event) (*http.Response, error) {
 
 	return new(http.Response), nil
@@ -84,12 +89,104 @@ func (m MockScheduler) Suppress() (*http.Response, error) {
 	return new(http.Response), nil
 }
 
+func (m MockScheduler) SubscribeContext(ctx context.Context, events chan *mesos_v1_scheduler.Event) (*http.Response, error) {
+	return new(http.Response), nil
+}
+
+func (m MockScheduler) TeardownContext(ctx context.Context) (*http.Response, error) {
+	return new(http.Response), nil
+}
+
+func (m MockScheduler) AcceptContext(ctx context.Context, offerIds []*mesos_v1.OfferID, tasks []*mesos_v1.Offer_Operation, filters *mesos_v1.Filters) (*http.Response, error) {
+	return new(http.Response), nil
+}
+
+func (m MockScheduler) DeclineContext(ctx context.Context, offerIds []*mesos_v1.OfferID, filters *mesos_v1.Filters) (*http.Response, error) {
+	return new(http.Response), nil
+}
+
+func (m MockScheduler) ReviveContext(ctx context.Context) (*http.Response, error) {
+	return new(http.Response), nil
+}
+
+func (m MockScheduler) KillContext(ctx context.Context, taskId *mesos_v1.TaskID, agentid *mesos_v1.AgentID) (*http.Response, error) {
+	return new(http.Response), nil
+}
+
+func (m MockScheduler) ShutdownContext(ctx context.Context, execId *mesos_v1.ExecutorID, agentId *mesos_v1.AgentID) (*http.Response, error) {
+	return new(http.Response), nil
+}
+
+func (m MockScheduler) AcknowledgeContext(ctx context.Context, agentId *mesos_v1.AgentID, taskId *mesos_v1.TaskID, uuid []byte) (*http.Response, error) {
+	return new(http.Response), nil
+}
+
+func (m MockScheduler) ReconcileContext(ctx context.Context, tasks []*mesos_v1.TaskInfo) (*http.Response, error) {
+	return new(http.Response), nil
+}
+
+func (m MockScheduler) MessageContext(ctx context.Context, agentId *mesos_v1.AgentID, executorId *mesos_v1.ExecutorID, data []byte) (*http.Response, error) {
+	return new(http.Response), nil
+}
+
+func (m MockScheduler) SchedRequestContext(ctx context.Context, resources []*mesos_v1.Request) (*http.Response, error) {
+	return new(http.Response), nil
+}
+
+func (m MockScheduler) SuppressContext(ctx context.Context) (*http.Response, error) {
+	return new(http.Response), nil
+}
+
+func (m MockScheduler) SuppressRoles(roles []string) (*http.Response, error) {
+	return new(http.Response), nil
+}
+
+func (m MockScheduler) ReviveRoles(roles []string) (*http.Response, error) {
+	return new(http.Response), nil
+}
+
+func (m MockScheduler) SuppressRolesContext(ctx context.Context, roles []string) (*http.Response, error) {
+	return new(http.Response), nil
+}
+
+func (m MockScheduler) ReviveRolesContext(ctx context.Context, roles []string) (*http.Response, error) {
+	return new(http.Response), nil
+}
+
+func (m MockScheduler) KillWithPolicy(taskId *mesos_v1.TaskID, agentid *mesos_v1.AgentID, policy *mesos_v1.KillPolicy) (*http.Response, error) {
+	return new(http.Response), nil
+}
+
+func (m MockScheduler) KillWithPolicyContext(ctx context.Context, taskId *mesos_v1.TaskID, agentid *mesos_v1.AgentID, policy *mesos_v1.KillPolicy) (*http.Response, error) {
+	return new(http.Response), nil
+}
+
+func (m MockScheduler) AcceptInverseOffers(offerIds []*mesos_v1.OfferID, filters *mesos_v1.Filters) (*http.Response, error) {
+	return new(http.Response), nil
+}
+
+func (m MockScheduler) DeclineInverseOffers(offerIds []*mesos_v1.OfferID, filters *mesos_v1.Filters) (*http.Response, error) {
+	return new(http.Response), nil
+}
+
+func (m MockScheduler) AcceptInverseOffersContext(ctx context.Context, offerIds []*mesos_v1.OfferID, filters *mesos_v1.Filters) (*http.Response, error) {
+	return new(http.Response), nil
+}
+
+func (m MockScheduler) DeclineInverseOffersContext(ctx context.Context, offerIds []*mesos_v1.OfferID, filters *mesos_v1.Filters) (*http.Response, error) {
+	return new(http.Response), nil
+}
+
 type MockBrokenScheduler struct{}
 
 func (m MockBrokenScheduler) FrameworkInfo() *mesos_v1.FrameworkInfo {
 	return nil
 }
 
+func (m MockBrokenScheduler) SetFrameworkId(id *mesos_v1.FrameworkID) {
+
+}
+
 func (m MockBrokenScheduler) Subscribe(chan *mesos_v1_scheduler.Event) (*http.Response, error) {
 	return new(http.Response), errors.New("Broken.")
 }
@@ -137,3 +234,91 @@ func (m MockBrokenScheduler) SchedRequest(resources []*mesos_v1.Request) (*http.
 func (m MockBrokenScheduler) Suppress() (*http.Response, error) {
 	return new(http.Response), errors.New("Broken.")
 }
+
+func (m MockBrokenScheduler) SubscribeContext(ctx context.Context, events chan *mesos_v1_scheduler.Event) (*http.Response, error) {
+	return new(http.Response), errors.New("Broken.")
+}
+
+func (m MockBrokenScheduler) TeardownContext(ctx context.Context) (*http.Response, error) {
+	return new(http.Response), errors.New("Broken.")
+}
+
+func (m MockBrokenScheduler) AcceptContext(ctx context.Context, offerIds []*mesos_v1.OfferID, tasks []*mesos_v1.Offer_Operation, filters *mesos_v1.Filters) (*http.Response, error) {
+	return new(http.Response), errors.New("Broken.")
+}
+
+func (m MockBrokenScheduler) DeclineContext(ctx context.Context, offerIds []*mesos_v1.OfferID, filters *mesos_v1.Filters) (*http.Response, error) {
+	return new(http.Response), errors.New("Broken.")
+}
+
+func (m MockBrokenScheduler) ReviveContext(ctx context.Context) (*http.Response, error) {
+	return new(http.Response), errors.New("Broken.")
+}
+
+func (m MockBrokenScheduler) KillContext(ctx context.Context, taskId *mesos_v1.TaskID, agentid *mesos_v1.AgentID) (*http.Response, error) {
+	return new(http.Response), errors.New("Broken.")
+}
+
+func (m MockBrokenScheduler) ShutdownContext(ctx context.Context, execId *mesos_v1.ExecutorID, agentId *mesos_v1.AgentID) (*http.Response, error) {
+	return new(http.Response), errors.New("Broken.")
+}
+
+func (m MockBrokenScheduler) AcknowledgeContext(ctx context.Context, agentId *mesos_v1.AgentID, taskId *mesos_v1.TaskID, uuid []byte) (*http.Response, error) {
+	return new(http.Response), errors.New("Broken.")
+}
+
+func (m MockBrokenScheduler) ReconcileContext(ctx context.Context, tasks []*mesos_v1.TaskInfo) (*http.Response, error) {
+	return new(http.Response), errors.New("Broken.")
+}
+
+func (m MockBrokenScheduler) MessageContext(ctx context.Context, agentId *mesos_v1.AgentID, executorId *mesos_v1.ExecutorID, data []byte) (*http.Response, error) {
+	return new(http.Response), errors.New("Broken.")
+}
+
+func (m MockBrokenScheduler) SchedRequestContext(ctx context.Context, resources []*mesos_v1.Request) (*http.Response, error) {
+	return new(http.Response), errors.New("Broken.")
+}
+
+func (m MockBrokenScheduler) SuppressContext(ctx context.Context) (*http.Response, error) {
+	return new(http.Response), errors.New("Broken.")
+}
+
+func (m MockBrokenScheduler) SuppressRoles(roles []string) (*http.Response, error) {
+	return new(http.Response), errors.New("Broken.")
+}
+
+func (m MockBrokenScheduler) ReviveRoles(roles []string) (*http.Response, error) {
+	return new(http.Response), errors.New("Broken.")
+}
+
+func (m MockBrokenScheduler) SuppressRolesContext(ctx context.Context, roles []string) (*http.Response, error) {
+	return new(http.Response), errors.New("Broken.")
+}
+
+func (m MockBrokenScheduler) ReviveRolesContext(ctx context.Context, roles []string) (*http.Response, error) {
+	return new(http.Response), errors.New("Broken.")
+}
+
+func (m MockBrokenScheduler) KillWithPolicy(taskId *mesos_v1.TaskID, agentid *mesos_v1.AgentID, policy *mesos_v1.KillPolicy) (*http.Response, error) {
+	return new(http.Response), errors.New("Broken.")
+}
+
+func (m MockBrokenScheduler) KillWithPolicyContext(ctx context.Context, taskId *mesos_v1.TaskID, agentid *mesos_v1.AgentID, policy *mesos_v1.KillPolicy) (*http.Response, error) {
+	return new(http.Response), errors.New("Broken.")
+}
+
+func (m MockBrokenScheduler) AcceptInverseOffers(offerIds []*mesos_v1.OfferID, filters *mesos_v1.Filters) (*http.Response, error) {
+	return new(http.Response), errors.New("Broken.")
+}
+
+func (m MockBrokenScheduler) DeclineInverseOffers(offerIds []*mesos_v1.OfferID, filters *mesos_v1.Filters) (*http.Response, error) {
+	return new(http.Response), errors.New("Broken.")
+}
+
+func (m MockBrokenScheduler) AcceptInverseOffersContext(ctx context.Context, offerIds []*mesos_v1.OfferID, filters *mesos_v1.Filters) (*http.Response, error) {
+	return new(http.Response), errors.New("Broken.")
+}
+
+func (m MockBrokenScheduler) DeclineInverseOffersContext(ctx context.Context, offerIds []*mesos_v1.OfferID, filters *mesos_v1.Filters) (*http.Response, error) {
+	return new(http.Response), errors.New("Broken.")
+}
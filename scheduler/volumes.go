@@ -0,0 +1,410 @@
+// Copyright 2017 Verizon
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package scheduler
+
+import (
+	"errors"
+	"github.com/golang/protobuf/proto"
+	"github.com/verizonlabs/mesos-framework-sdk/include/mesos_v1"
+	"github.com/verizonlabs/mesos-framework-sdk/logging"
+	"github.com/verizonlabs/mesos-framework-sdk/resources"
+	"github.com/verizonlabs/mesos-framework-sdk/utils"
+	"sync"
+)
+
+/*
+PersistentVolumeWorkflow walks stateful tasks through the offer operations needed to run on persistent volumes.
+
+Each task goes through the following steps, one offer at a time:
+ 1. Reserve the task's resources plus the volume's disk from an offer.
+ 2. Create the persistent volume once the reservation comes back in an offer from the same agent.
+ 3. Launch the task onto its reserved resources and volume.
+ 4. Once retired, destroy the volume and unreserve everything.
+
+Reservations are labeled with the task ID so that we can recognize them in later offers.
+If a step is lost along the way, such as when an offer is rescinded, the task falls back to an earlier step.
+*/
+type PersistentVolumeWorkflow struct {
+	scheduler Scheduler
+	role      string
+	principal string
+	logger    logging.Logger
+	plans     map[string]*volumePlan // Keyed by task ID.
+	lock      sync.Mutex
+}
+
+// Steps a task goes through in the workflow.
+type VolumeStage uint8
+
+const (
+	RESERVING VolumeStage = iota
+	CREATING
+	LAUNCHING
+	LAUNCHED
+	RETIRING
+)
+
+func (s VolumeStage) String() string {
+	switch s {
+	case RESERVING:
+		return "RESERVING"
+	case CREATING:
+		return "CREATING"
+	case LAUNCHING:
+		return "LAUNCHING"
+	case LAUNCHED:
+		return "LAUNCHED"
+	case RETIRING:
+		return "RETIRING"
+	}
+
+	return "UNKNOWN"
+}
+
+// Tracks where a single task is in the workflow.
+type volumePlan struct {
+	task          *mesos_v1.TaskInfo
+	size          float64
+	containerPath string
+	agentId       *mesos_v1.AgentID
+	stage         VolumeStage
+	accepting     bool // An accept moving the task forward is in flight.
+}
+
+// Label used to tie reservations and volumes back to their task.
+const persistenceLabel = "persistence_id"
+
+func NewPersistentVolumeWorkflow(s Scheduler, role, principal string, logger logging.Logger) *PersistentVolumeWorkflow {
+	return &PersistentVolumeWorkflow{
+		scheduler: s,
+		role:      role,
+		principal: principal,
+		logger:    logger,
+		plans:     make(map[string]*volumePlan),
+	}
+}
+
+// Adds a task that needs a persistent volume of the given size in MB, mounted at the container path.
+// The task's resources must be unreserved scalars, they're reserved for it as part of the workflow.
+func (w *PersistentVolumeWorkflow) Add(task *mesos_v1.TaskInfo, size float64, containerPath string) error {
+	if size <= 0 {
+		return errors.New("Persistent volume size must be greater than 0")
+	}
+	for _, r := range task.GetResources() {
+		if r.GetType() != mesos_v1.Value_SCALAR {
+			return errors.New("Task resource " + r.GetName() + " is not a scalar and can't be reserved")
+		}
+	}
+
+	w.lock.Lock()
+	defer w.lock.Unlock()
+
+	id := task.GetTaskId().GetValue()
+	if _, ok := w.plans[id]; ok {
+		return errors.New("Task " + id + " already has a persistent volume")
+	}
+
+	w.plans[id] = &volumePlan{
+		task:          task,
+		size:          size,
+		containerPath: containerPath,
+		stage:         RESERVING,
+	}
+
+	return nil
+}
+
+// Retires a task, killing it if needed.
+// Its volume and reservations are cleaned up once they're offered back to us.
+func (w *PersistentVolumeWorkflow) Retire(taskId *mesos_v1.TaskID) error {
+	w.lock.Lock()
+	plan, ok := w.plans[taskId.GetValue()]
+	if !ok {
+		w.lock.Unlock()
+		return errors.New("Task " + taskId.GetValue() + " does not have a persistent volume")
+	}
+
+	// Nothing has been reserved yet so there's nothing to clean up.
+	if plan.stage == RESERVING && !plan.accepting {
+		delete(w.plans, taskId.GetValue())
+		w.lock.Unlock()
+		return nil
+	}
+
+	// Tasks that are still being launched are killed once the launch goes through.
+	launched := plan.stage == LAUNCHED
+	plan.stage = RETIRING
+	w.lock.Unlock()
+
+	if launched {
+		return w.kill(taskId, plan.agentId)
+	}
+
+	return nil
+}
+
+func (w *PersistentVolumeWorkflow) kill(taskId *mesos_v1.TaskID, agentId *mesos_v1.AgentID) error {
+	resp, err := w.scheduler.Kill(taskId, agentId)
+	closeBody(resp)
+
+	return err
+}
+
+// Gets the stage a task is currently in.
+func (w *PersistentVolumeWorkflow) Stage(taskId *mesos_v1.TaskID) (VolumeStage, bool) {
+	w.lock.Lock()
+	defer w.lock.Unlock()
+
+	plan, ok := w.plans[taskId.GetValue()]
+	if !ok {
+		return 0, false
+	}
+
+	return plan.stage, true
+}
+
+// Moves a single task forward using the offer, if any task can make use of it.
+// Tells us if the offer was accepted, otherwise it's up to the caller to use or decline it.
+// Tasks only move on to their next stage once the accept goes through.
+func (w *PersistentVolumeWorkflow) Offer(offer *mesos_v1.Offer) (bool, error) {
+	w.lock.Lock()
+
+	var id string
+	var plan *volumePlan
+	var stage VolumeStage
+	var ops []*mesos_v1.Offer_Operation
+	for id, plan = range w.plans {
+		if plan.accepting {
+			continue
+		}
+
+		var err error
+		stage, ops, err = w.operations(plan, offer)
+		if err != nil {
+			w.lock.Unlock()
+			return false, err
+		}
+		if ops != nil {
+			break
+		}
+	}
+
+	if ops == nil {
+		w.lock.Unlock()
+		return false, nil
+	}
+
+	plan.accepting = true
+	w.lock.Unlock()
+
+	resp, err := w.scheduler.Accept([]*mesos_v1.OfferID{offer.GetId()}, ops, nil)
+	closeBody(resp)
+
+	w.lock.Lock()
+	plan.accepting = false
+	if err != nil {
+		w.lock.Unlock()
+		return false, err
+	}
+
+	// The task may have been retired while we were accepting, in which case it stays retiring.
+	retired := plan.stage == RETIRING && stage != RETIRING
+	switch stage {
+	case RESERVING:
+		plan.agentId = offer.GetAgentId()
+		plan.stage = CREATING
+	case CREATING:
+		plan.stage = LAUNCHING
+	case LAUNCHING:
+		plan.stage = LAUNCHED
+	case RETIRING:
+		delete(w.plans, id)
+	}
+	if retired {
+		plan.stage = RETIRING
+	}
+	w.lock.Unlock()
+
+	w.logger.Emit(logging.INFO, "Offer %s accepted for task %s while %s", offer.GetId().GetValue(), id, stage)
+
+	if retired && stage == LAUNCHING {
+		return true, w.kill(plan.task.GetTaskId(), plan.agentId)
+	}
+
+	return true, nil
+}
+
+// Figures out which operations move the task forward, if the offer can be used at all.
+// We may have to fall back to an earlier stage if our previous operations never made it, so the stage the operations
+// are for is handed back as well. The plan itself is left alone.
+// Errors mean the offer can't be reserved the way it is.
+func (w *PersistentVolumeWorkflow) operations(plan *volumePlan, offer *mesos_v1.Offer) (VolumeStage, []*mesos_v1.Offer_Operation, error) {
+	id := plan.task.GetTaskId().GetValue()
+	stage := plan.stage
+	if stage != RESERVING && offer.GetAgentId().GetValue() != plan.agentId.GetValue() {
+		return stage, nil, nil
+	}
+
+	reserved, disk, volume := w.reserved(id, offer.GetResources())
+
+	if stage == CREATING || stage == LAUNCHING {
+		if len(reserved) == 0 {
+			stage = RESERVING
+		} else if stage == LAUNCHING && volume == nil && disk != nil {
+			stage = CREATING
+		}
+	}
+
+	switch stage {
+	case RESERVING:
+		res, err := w.reservation(plan, offer.GetResources())
+		if res == nil {
+			return stage, nil, err
+		}
+
+		return stage, []*mesos_v1.Offer_Operation{resources.ReserveOfferOperation(res)}, nil
+	case CREATING:
+		if disk == nil {
+			return stage, nil, nil
+		}
+
+		volume := resources.CreatePersistentVolume(disk, id, w.principal, plan.containerPath)
+
+		return stage, []*mesos_v1.Offer_Operation{resources.CreateVolumesOfferOperation([]*mesos_v1.Resource{volume})}, nil
+	case LAUNCHING:
+		if volume == nil {
+			return stage, nil, nil
+		}
+
+		task := proto.Clone(plan.task).(*mesos_v1.TaskInfo)
+		task.AgentId = offer.GetAgentId()
+		task.Resources = reserved
+
+		return stage, []*mesos_v1.Offer_Operation{resources.LaunchOfferOperation([]*mesos_v1.TaskInfo{task})}, nil
+	case RETIRING:
+		if len(reserved) == 0 {
+			return stage, nil, nil
+		}
+
+		var ops []*mesos_v1.Offer_Operation
+		unreserve := make([]*mesos_v1.Resource, 0, len(reserved))
+		for _, r := range reserved {
+			r = proto.Clone(r).(*mesos_v1.Resource)
+			r.Disk = nil
+			unreserve = append(unreserve, r)
+		}
+
+		if volume != nil {
+			ops = append(ops, resources.DestroyVolumesOfferOperation([]*mesos_v1.Resource{volume}))
+		}
+
+		return stage, append(ops, resources.UnreserveOfferOperation(unreserve)), nil
+	}
+
+	return stage, nil, nil
+}
+
+// Finds everything reserved for the task in the offer, along with its disk and volume if they exist.
+func (w *PersistentVolumeWorkflow) reserved(id string, offered []*mesos_v1.Resource) ([]*mesos_v1.Resource, *mesos_v1.Resource, *mesos_v1.Resource) {
+	var reserved []*mesos_v1.Resource
+	var disk, volume *mesos_v1.Resource
+
+	for _, r := range offered {
+		if r.GetRole() != w.role || !hasLabel(r.GetReservation().GetLabels(), persistenceLabel, id) {
+			continue
+		}
+
+		reserved = append(reserved, r)
+		if r.GetName() != "disk" {
+			continue
+		}
+
+		if r.GetDisk().GetPersistence().GetId() == id {
+			volume = r
+		} else if r.GetDisk().GetPersistence() == nil {
+			disk = r
+		}
+	}
+
+	return reserved, disk, volume
+}
+
+// Builds the resources to reserve for the task if the offer has enough unreserved resources for it.
+// Only scalars can be reserved, and unreserved resources allocated to different roles can't be reserved together.
+func (w *PersistentVolumeWorkflow) reservation(plan *volumePlan, offered []*mesos_v1.Resource) ([]*mesos_v1.Resource, error) {
+	needed := make(map[string]float64)
+	var names []string
+	for _, r := range plan.task.GetResources() {
+		if r.GetType() != mesos_v1.Value_SCALAR {
+			return nil, errors.New("Task resource " + r.GetName() + " is not a scalar and can't be reserved")
+		}
+		if _, ok := needed[r.GetName()]; !ok {
+			names = append(names, r.GetName())
+		}
+		needed[r.GetName()] += r.GetScalar().GetValue()
+	}
+	if _, ok := needed["disk"]; !ok {
+		names = append(names, "disk")
+	}
+	needed["disk"] += plan.size
+
+	// Non-scalars such as ports are left alone, none of the task's resources are anything else.
+	available := make(map[string]float64)
+	allocations := make(map[string]*mesos_v1.Resource_AllocationInfo)
+	for _, r := range offered {
+		if r.GetRole() != mesos_v1.Default_Resource_Role || r.Reservation != nil || r.Disk != nil || r.GetType() != mesos_v1.Value_SCALAR {
+			continue
+		}
+
+		for _, other := range allocations {
+			if other.GetRole() != r.GetAllocationInfo().GetRole() {
+				return nil, errors.New("Offered resources are allocated to more than one role and can't be reserved together")
+			}
+		}
+
+		available[r.GetName()] += r.GetScalar().GetValue()
+		allocations[r.GetName()] = r.GetAllocationInfo()
+	}
+
+	labels := &mesos_v1.Labels{
+		Labels: []*mesos_v1.Label{
+			{Key: utils.ProtoString(persistenceLabel), Value: plan.task.GetTaskId().Value},
+		},
+	}
+
+	res := make([]*mesos_v1.Resource, 0, len(names))
+	for _, name := range names {
+		if available[name] < needed[name] {
+			return nil, nil
+		}
+
+		r := resources.CreateReservedResource(name, w.role, w.principal, needed[name], labels)
+		r.AllocationInfo = allocations[name]
+		res = append(res, r)
+	}
+
+	return res, nil
+}
+
+// Checks if the labels contain the given key and value.
+func hasLabel(labels *mesos_v1.Labels, key, value string) bool {
+	for _, l := range labels.GetLabels() {
+		if l.GetKey() == key && l.GetValue() == value {
+			return true
+		}
+	}
+
+	return false
+}
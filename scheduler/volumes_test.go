@@ -0,0 +1,225 @@
+// Copyright 2017 Verizon
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package scheduler
+
+import (
+	"github.com/golang/protobuf/proto"
+	"github.com/verizonlabs/mesos-framework-sdk/include/mesos_v1"
+	"github.com/verizonlabs/mesos-framework-sdk/include/mesos_v1_scheduler"
+	"github.com/verizonlabs/mesos-framework-sdk/resources"
+	"testing"
+)
+
+func volumeOffer(id, agent string, res []*mesos_v1.Resource) *mesos_v1.Offer {
+	return &mesos_v1.Offer{
+		Id:        &mesos_v1.OfferID{Value: proto.String(id)},
+		AgentId:   &mesos_v1.AgentID{Value: proto.String(agent)},
+		Resources: res,
+	}
+}
+
+// Gets the operations from the last accept call made.
+func lastOperations(t *testing.T, r *recordingClient) []*mesos_v1.Offer_Operation {
+	accepts := r.byType(mesos_v1_scheduler.Call_ACCEPT)
+	if len(accepts) == 0 {
+		t.Fatal("No offers were accepted")
+	}
+
+	return accepts[len(accepts)-1].Accept.Operations
+}
+
+// Walks a task through reserving, creating its volume, launching and cleaning up.
+func TestPersistentVolumeWorkflow_Offer(t *testing.T) {
+	t.Parallel()
+
+	r := new(recordingClient)
+	w := NewPersistentVolumeWorkflow(NewDefaultScheduler(r, i, l), "stateful", "principal", l)
+
+	taskId := &mesos_v1.TaskID{Value: proto.String("task")}
+	err := w.Add(&mesos_v1.TaskInfo{
+		Name:   proto.String("task"),
+		TaskId: taskId,
+		Resources: []*mesos_v1.Resource{
+			resources.CreateResource("cpus", "", 1),
+			resources.CreateResource("mem", "", 128),
+		},
+	}, 100, "data")
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	// Too small to hold the task.
+	used, _ := w.Offer(volumeOffer("small", "agent", []*mesos_v1.Resource{
+		resources.CreateResource("cpus", "*", 4),
+		resources.CreateResource("mem", "*", 64),
+		resources.CreateResource("disk", "*", 1000),
+	}))
+	if used {
+		t.Fatal("Offer without enough memory should not have been used")
+	}
+
+	used, _ = w.Offer(volumeOffer("reserve", "agent", []*mesos_v1.Resource{
+		resources.CreateResource("cpus", "*", 4),
+		resources.CreateResource("mem", "*", 1024),
+		resources.CreateResource("disk", "*", 1000),
+	}))
+	ops := lastOperations(t, r)
+	if !used || ops[0].GetType() != mesos_v1.Offer_Operation_RESERVE || len(ops[0].Reserve.Resources) != 3 {
+		t.Fatal("Resources should have been reserved")
+	}
+	reserved := ops[0].Reserve.Resources
+
+	used, _ = w.Offer(volumeOffer("elsewhere", "other", reserved))
+	if used {
+		t.Fatal("Offers from other agents should not be used once resources are reserved")
+	}
+
+	used, _ = w.Offer(volumeOffer("create", "agent", reserved))
+	ops = lastOperations(t, r)
+	if !used || ops[0].GetType() != mesos_v1.Offer_Operation_CREATE {
+		t.Fatal("Persistent volume should have been created")
+	}
+
+	volume := ops[0].Create.Volumes[0]
+	if volume.GetDisk().GetPersistence().GetId() != "task" || volume.GetDisk().GetVolume().GetContainerPath() != "data" || volume.GetScalar().GetValue() != 100 {
+		t.Fatal("Persistent volume is wrong: " + volume.String())
+	}
+
+	withVolume := []*mesos_v1.Resource{reserved[0], reserved[1], volume}
+	used, _ = w.Offer(volumeOffer("launch", "agent", withVolume))
+	ops = lastOperations(t, r)
+	if !used || ops[0].GetType() != mesos_v1.Offer_Operation_LAUNCH || len(ops[0].Launch.TaskInfos[0].Resources) != 3 {
+		t.Fatal("Task should have been launched onto its volume")
+	}
+
+	if stage, _ := w.Stage(taskId); stage != LAUNCHED {
+		t.Fatal("Task should have been launched, it's " + stage.String())
+	}
+
+	if err := w.Retire(taskId); err != nil || len(r.byType(mesos_v1_scheduler.Call_KILL)) != 1 {
+		t.Fatal("Task should have been killed when retired")
+	}
+
+	used, _ = w.Offer(volumeOffer("cleanup", "agent", withVolume))
+	ops = lastOperations(t, r)
+	if !used || len(ops) != 2 || ops[0].GetType() != mesos_v1.Offer_Operation_DESTROY || ops[1].GetType() != mesos_v1.Offer_Operation_UNRESERVE {
+		t.Fatal("Volume should have been destroyed and resources unreserved")
+	}
+
+	for _, res := range ops[1].Unreserve.Resources {
+		if res.Disk != nil {
+			t.Fatal("Unreserved disk should no longer be a volume")
+		}
+	}
+
+	if _, ok := w.Stage(taskId); ok {
+		t.Fatal("Retired task should no longer be tracked")
+	}
+}
+
+// Makes sure we start over when our reservation never made it.
+func TestPersistentVolumeWorkflow_OfferLostReservation(t *testing.T) {
+	t.Parallel()
+
+	r := new(recordingClient)
+	w := NewPersistentVolumeWorkflow(NewDefaultScheduler(r, i, l), "stateful", "principal", l)
+
+	taskId := &mesos_v1.TaskID{Value: proto.String("task")}
+	w.Add(&mesos_v1.TaskInfo{TaskId: taskId}, 100, "data")
+
+	unreserved := volumeOffer("offer", "agent", []*mesos_v1.Resource{resources.CreateResource("disk", "*", 1000)})
+	w.Offer(unreserved)
+	w.Offer(unreserved)
+
+	ops := lastOperations(t, r)
+	if len(r.byType(mesos_v1_scheduler.Call_ACCEPT)) != 2 || ops[0].GetType() != mesos_v1.Offer_Operation_RESERVE {
+		t.Fatal("Resources should have been reserved again")
+	}
+
+	if w.Add(&mesos_v1.TaskInfo{TaskId: taskId}, 100, "data") == nil {
+		t.Fatal("Tasks should only be added once")
+	}
+}
+
+// Ensures offers we can't use leave the task where it was.
+func TestPersistentVolumeWorkflow_OfferUnused(t *testing.T) {
+	t.Parallel()
+
+	r := new(recordingClient)
+	w := NewPersistentVolumeWorkflow(NewDefaultScheduler(r, i, l), "stateful", "principal", l)
+
+	taskId := &mesos_v1.TaskID{Value: proto.String("task")}
+	w.Add(&mesos_v1.TaskInfo{TaskId: taskId}, 100, "data")
+	w.Offer(volumeOffer("reserve", "agent", []*mesos_v1.Resource{resources.CreateResource("disk", "*", 1000)}))
+
+	used, _ := w.Offer(volumeOffer("tiny", "agent", []*mesos_v1.Resource{resources.CreateResource("disk", "*", 1)}))
+	if used {
+		t.Fatal("Offer without our reservation or room for a new one should not have been used")
+	}
+
+	if stage, _ := w.Stage(taskId); stage != CREATING {
+		t.Fatal("Task should still be creating its volume, it's " + stage.String())
+	}
+}
+
+// Checks that only tasks with scalar resources can have them reserved.
+func TestPersistentVolumeWorkflow_AddNonScalar(t *testing.T) {
+	t.Parallel()
+
+	w := NewPersistentVolumeWorkflow(NewDefaultScheduler(new(recordingClient), i, l), "stateful", "principal", l)
+	err := w.Add(&mesos_v1.TaskInfo{
+		TaskId: &mesos_v1.TaskID{Value: proto.String("task")},
+		Resources: []*mesos_v1.Resource{
+			resources.CreateResource("cpus", "", 1),
+			{Name: proto.String("ports"), Type: mesos_v1.Value_RANGES.Enum()},
+		},
+	}, 100, "data")
+	if err == nil {
+		t.Fatal("Tasks asking for non-scalar resources should be rejected")
+	}
+}
+
+// Makes sure each reserved resource keeps the allocation of the resource it was reserved from.
+func TestPersistentVolumeWorkflow_OfferAllocation(t *testing.T) {
+	t.Parallel()
+
+	r := new(recordingClient)
+	w := NewPersistentVolumeWorkflow(NewDefaultScheduler(r, i, l), "stateful", "principal", l)
+	w.Add(&mesos_v1.TaskInfo{
+		TaskId:    &mesos_v1.TaskID{Value: proto.String("task")},
+		Resources: []*mesos_v1.Resource{resources.CreateResource("cpus", "", 1)},
+	}, 100, "data")
+
+	allocated := func(name, role string, value float64) *mesos_v1.Resource {
+		res := resources.CreateResource(name, "*", value)
+		res.AllocationInfo = &mesos_v1.Resource_AllocationInfo{Role: proto.String(role)}
+		return res
+	}
+
+	mixed := []*mesos_v1.Resource{allocated("cpus", "web", 4), allocated("disk", "db", 1000)}
+	if used, err := w.Offer(volumeOffer("mixed", "agent", mixed)); used || err == nil {
+		t.Fatal("Resources allocated to different roles should not be reserved together")
+	}
+
+	offered := []*mesos_v1.Resource{allocated("cpus", "web", 4), allocated("disk", "web", 1000)}
+	if used, err := w.Offer(volumeOffer("offer", "agent", offered)); !used || err != nil {
+		t.Fatal("Resources should have been reserved")
+	}
+
+	reserved := lastOperations(t, r)[0].Reserve.Resources
+	if len(reserved) != 2 || reserved[0].AllocationInfo != offered[0].AllocationInfo || reserved[1].AllocationInfo != offered[1].AllocationInfo {
+		t.Fatal("Reserved resources should be allocated the same way as what they were reserved from")
+	}
+}
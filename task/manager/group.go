@@ -0,0 +1,218 @@
+// Copyright 2017 Verizon
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package manager
+
+import (
+	"errors"
+	"github.com/verizonlabs/mesos-framework-sdk/include/mesos_v1"
+	"github.com/verizonlabs/mesos-framework-sdk/task"
+	"github.com/verizonlabs/mesos-framework-sdk/task/retry"
+	"sync"
+)
+
+// Task groups are launched, tracked and rescheduled as a single unit.
+// Each member is still a regular task in the task manager so that status updates can be matched up as usual,
+// with the group's name and executor recorded in its GroupInfo.
+type TaskGroup struct {
+	Name     string
+	Executor *mesos_v1.ExecutorInfo
+	Tasks    []*Task
+}
+
+// Keeps track of task groups so that their members are added and removed together.
+// This sits next to the task manager instead of being part of it, so existing task managers keep working.
+type GroupManager interface {
+	AddGroup(...*TaskGroup) error
+	GetTaskGroup(name string) (*TaskGroup, error)
+	DeleteGroup(...*TaskGroup) error
+}
+
+// Tracks groups by name on top of any task manager, which still holds every member as a regular task.
+type DefaultGroupManager struct {
+	tasks  TaskManager
+	groups map[string]*TaskGroup
+	lock   sync.RWMutex
+}
+
+func NewDefaultGroupManager(tasks TaskManager) *DefaultGroupManager {
+	return &DefaultGroupManager{
+		tasks:  tasks,
+		groups: make(map[string]*TaskGroup),
+	}
+}
+
+// Adds every member of each group to the task manager.
+// If the task manager refuses any member, the ones already added are taken back out so the group isn't left half tracked.
+func (m *DefaultGroupManager) AddGroup(groups ...*TaskGroup) error {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	for _, g := range groups {
+		if _, ok := m.groups[g.Name]; ok {
+			return errors.New("Task group " + g.Name + " already exists")
+		}
+
+		for i, t := range g.Tasks {
+			if err := m.tasks.Add(t); err != nil {
+				if i > 0 {
+					m.tasks.Delete(g.Tasks[:i]...)
+				}
+				return err
+			}
+		}
+		m.groups[g.Name] = g
+	}
+
+	return nil
+}
+
+// Gets a group we're tracking by its name.
+func (m *DefaultGroupManager) GetTaskGroup(name string) (*TaskGroup, error) {
+	m.lock.RLock()
+	defer m.lock.RUnlock()
+
+	g, ok := m.groups[name]
+	if !ok {
+		return nil, errors.New("Task group " + name + " not found")
+	}
+
+	return g, nil
+}
+
+// Removes every member of each group from the task manager and stops tracking the groups.
+func (m *DefaultGroupManager) DeleteGroup(groups ...*TaskGroup) error {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	for _, g := range groups {
+		if err := m.tasks.Delete(g.Tasks...); err != nil {
+			return err
+		}
+		delete(m.groups, g.Name)
+	}
+
+	return nil
+}
+
+// Creates a new group with all of its members in the same initial state.
+func NewTaskGroup(
+	name string,
+	executor *mesos_v1.ExecutorInfo,
+	infos []*mesos_v1.TaskInfo,
+	s mesos_v1.TaskState,
+	f []task.Filter,
+	r *retry.TaskRetry,
+	strategy task.Strategy) *TaskGroup {
+
+	g := GroupInfo{
+		GroupName: name,
+		InGroup:   true,
+		Executor:  executor,
+	}
+
+	tasks := make([]*Task, 0, len(infos))
+	for _, info := range infos {
+		t := NewTask(info, s, f, r, 1, g)
+		t.Strategy = strategy
+		tasks = append(tasks, t)
+	}
+
+	return &TaskGroup{
+		Name:     name,
+		Executor: executor,
+		Tasks:    tasks,
+	}
+}
+
+// Rebuilds a group from its members, such as the ones returned by GetGroup.
+func TaskGroupFromTasks(tasks []*Task) (*TaskGroup, error) {
+	if len(tasks) == 0 {
+		return nil, errors.New("Task group has no members")
+	}
+
+	g := tasks[0].GroupInfo
+	for _, t := range tasks {
+		if !t.GroupInfo.InGroup || t.GroupInfo.GroupName != g.GroupName {
+			return nil, errors.New("Tasks do not belong to the same group")
+		}
+	}
+
+	return &TaskGroup{
+		Name:     g.GroupName,
+		Executor: g.Executor,
+		Tasks:    tasks,
+	}, nil
+}
+
+// Gets the task infos needed to launch the group.
+func (g *TaskGroup) Infos() []*mesos_v1.TaskInfo {
+	infos := make([]*mesos_v1.TaskInfo, 0, len(g.Tasks))
+	for _, t := range g.Tasks {
+		infos = append(infos, t.Info)
+	}
+
+	return infos
+}
+
+// Places every member on the given agent, which is required before launching.
+func (g *TaskGroup) SetAgent(agentId *mesos_v1.AgentID) {
+	for _, t := range g.Tasks {
+		t.Info.AgentId = agentId
+	}
+}
+
+// Sums up the state of the whole group.
+// A group is only running once all members are, and any member failing takes down the entire group
+// since the default executor kills the rest of the group when that happens.
+func (g *TaskGroup) State() mesos_v1.TaskState {
+	if len(g.Tasks) == 0 {
+		return UNKNOWN
+	}
+
+	finished := 0
+	var pending *mesos_v1.TaskState
+	for _, t := range g.Tasks {
+		switch t.State {
+		case FAILED, ERROR, LOST, DROPPED, GONE, GONE_BY_OPERATOR, KILLED:
+			return t.State
+		case FINISHED:
+			finished++
+		case RUNNING:
+			// Only matters if nobody else is behind.
+		default:
+			if pending == nil {
+				state := t.State
+				pending = &state
+			}
+		}
+	}
+
+	if finished == len(g.Tasks) {
+		return FINISHED
+	}
+
+	if pending != nil {
+		return *pending
+	}
+
+	return RUNNING
+}
+
+// Updates the state of every member, such as when the group is rescheduled.
+func (g *TaskGroup) SetState(state mesos_v1.TaskState) {
+	for _, t := range g.Tasks {
+		t.State = state
+	}
+}
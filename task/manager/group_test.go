@@ -0,0 +1,194 @@
+// Copyright 2017 Verizon
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package manager
+
+import (
+	"errors"
+	"github.com/golang/protobuf/proto"
+	"github.com/verizonlabs/mesos-framework-sdk/include/mesos_v1"
+	"github.com/verizonlabs/mesos-framework-sdk/task"
+	"testing"
+)
+
+func mockGroup(states ...mesos_v1.TaskState) *TaskGroup {
+	infos := make([]*mesos_v1.TaskInfo, 0, len(states))
+	for range states {
+		infos = append(infos, &mesos_v1.TaskInfo{Name: proto.String("member")})
+	}
+
+	g := NewTaskGroup("group", &mesos_v1.ExecutorInfo{}, infos, UNKNOWN, nil, nil, task.Strategy{})
+	for i, s := range states {
+		g.Tasks[i].State = s
+	}
+
+	return g
+}
+
+// Checks how the state of the members adds up to the state of the group.
+func TestTaskGroup_State(t *testing.T) {
+	t.Parallel()
+
+	cases := []struct {
+		members []mesos_v1.TaskState
+		want    mesos_v1.TaskState
+	}{
+		{nil, UNKNOWN},
+		{[]mesos_v1.TaskState{RUNNING, RUNNING}, RUNNING},
+		{[]mesos_v1.TaskState{RUNNING, STAGING}, STAGING},
+		{[]mesos_v1.TaskState{STARTING, STAGING}, STARTING},
+		{[]mesos_v1.TaskState{FINISHED, FINISHED}, FINISHED},
+		{[]mesos_v1.TaskState{FINISHED, RUNNING}, RUNNING},
+		{[]mesos_v1.TaskState{RUNNING, FAILED}, FAILED},
+		{[]mesos_v1.TaskState{FINISHED, KILLED, STAGING}, KILLED},
+		{[]mesos_v1.TaskState{LOST, RUNNING}, LOST},
+	}
+
+	for _, c := range cases {
+		if state := mockGroup(c.members...).State(); state != c.want {
+			t.Fatal("Members in", c.members, "should add up to", c.want.String(), "but got", state.String())
+		}
+	}
+}
+
+// Makes sure groups can only be rebuilt from members of the same group.
+func TestTaskGroupFromTasks(t *testing.T) {
+	t.Parallel()
+
+	g := mockGroup(RUNNING, RUNNING)
+	rebuilt, err := TaskGroupFromTasks(g.Tasks)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	if rebuilt.Name != g.Name || rebuilt.Executor != g.Executor {
+		t.Fatal("The rebuilt group should match the original")
+	}
+
+	if _, err := TaskGroupFromTasks(nil); err == nil {
+		t.Fatal("A group needs at least one member")
+	}
+
+	other := mockGroup(RUNNING)
+	other.Tasks[0].GroupInfo.GroupName = "other"
+	if _, err := TaskGroupFromTasks(append(g.Tasks, other.Tasks...)); err == nil {
+		t.Fatal("Tasks from different groups should not make up a group")
+	}
+}
+
+// Checks that state and agent changes apply to every member.
+func TestTaskGroup_SetStateAndAgent(t *testing.T) {
+	t.Parallel()
+
+	g := mockGroup(RUNNING, FAILED)
+	g.SetState(STAGING)
+	g.SetAgent(&mesos_v1.AgentID{Value: proto.String("agent")})
+
+	for _, member := range g.Tasks {
+		if member.State != STAGING || member.Info.GetAgentId().GetValue() != "agent" {
+			t.Fatal("Every member should have been updated")
+		}
+	}
+	if len(g.Infos()) != 2 {
+		t.Fatal("Every member should be launched")
+	}
+}
+
+// Holds tasks in memory, refusing any task named "broken".
+type memoryTaskManager struct {
+	TaskManager
+	tasks map[string]*Task
+}
+
+func (m *memoryTaskManager) Add(tasks ...*Task) error {
+	for _, t := range tasks {
+		if t.Info.GetName() == "broken" {
+			return errors.New("Broken task")
+		}
+		m.tasks[t.Info.GetTaskId().GetValue()] = t
+	}
+
+	return nil
+}
+
+func (m *memoryTaskManager) Delete(tasks ...*Task) error {
+	for _, t := range tasks {
+		delete(m.tasks, t.Info.GetTaskId().GetValue())
+	}
+
+	return nil
+}
+
+func namedGroup(name string, members ...string) *TaskGroup {
+	infos := make([]*mesos_v1.TaskInfo, 0, len(members))
+	for _, m := range members {
+		infos = append(infos, &mesos_v1.TaskInfo{
+			Name:   proto.String(m),
+			TaskId: &mesos_v1.TaskID{Value: proto.String(name + "-" + m)},
+		})
+	}
+
+	return NewTaskGroup(name, &mesos_v1.ExecutorInfo{}, infos, UNKNOWN, nil, nil, task.Strategy{})
+}
+
+// Makes sure groups are added, looked up and deleted as a whole.
+func TestDefaultGroupManager(t *testing.T) {
+	t.Parallel()
+
+	tasks := &memoryTaskManager{tasks: make(map[string]*Task)}
+	m := NewDefaultGroupManager(tasks)
+
+	g := namedGroup("pod", "server", "sidecar")
+	if err := m.AddGroup(g); err != nil {
+		t.Fatal(err.Error())
+	}
+	if len(tasks.tasks) != 2 {
+		t.Fatal("Every member should have been added to the task manager")
+	}
+
+	found, err := m.GetTaskGroup("pod")
+	if err != nil || found != g {
+		t.Fatal("The group should be found by its name")
+	}
+	if err := m.AddGroup(namedGroup("pod", "other")); err == nil {
+		t.Fatal("Groups with the same name should be rejected")
+	}
+
+	if err := m.DeleteGroup(g); err != nil {
+		t.Fatal(err.Error())
+	}
+	if len(tasks.tasks) != 0 {
+		t.Fatal("Every member should have been deleted from the task manager")
+	}
+	if _, err := m.GetTaskGroup("pod"); err == nil {
+		t.Fatal("Deleted groups should no longer be found")
+	}
+}
+
+// Checks that a group whose members can't all be added isn't left half tracked.
+func TestDefaultGroupManager_AddFailure(t *testing.T) {
+	t.Parallel()
+
+	tasks := &memoryTaskManager{tasks: make(map[string]*Task)}
+	m := NewDefaultGroupManager(tasks)
+
+	if err := m.AddGroup(namedGroup("pod", "server", "broken")); err == nil {
+		t.Fatal("The group should not have been added")
+	}
+	if len(tasks.tasks) != 0 {
+		t.Fatal("Members that were added should have been taken back out")
+	}
+	if _, err := m.GetTaskGroup("pod"); err == nil {
+		t.Fatal("The group should not be tracked")
+	}
+}
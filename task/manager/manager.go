@@ -70,11 +70,16 @@ type Task struct {
 	IsKill    bool
 	GroupInfo GroupInfo
 	Strategy  task.Strategy
+	Role      string // Role the task consumes resources from when the framework has several, empty for any of them.
+
+	// Resources the task asked for, set once it's assigned an offer since Info.Resources then holds exactly what it got.
+	Requested []*mesos_v1.Resource
 }
 
 type GroupInfo struct {
 	GroupName string
 	InGroup   bool
+	Executor  *mesos_v1.ExecutorInfo // Set for task groups that are launched together on the default executor.
 }
 
 func NewTask(i *mesos_v1.TaskInfo, s mesos_v1.TaskState, f []task.Filter, r *retry.TaskRetry, n int, g GroupInfo) *Task {
@@ -0,0 +1,285 @@
+// Copyright 2017 Verizon
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package pod
+
+import (
+	"errors"
+	"github.com/verizonlabs/mesos-framework-sdk/include/mesos_v1"
+	"github.com/verizonlabs/mesos-framework-sdk/resources"
+	"github.com/verizonlabs/mesos-framework-sdk/task"
+	"github.com/verizonlabs/mesos-framework-sdk/task/command"
+	"github.com/verizonlabs/mesos-framework-sdk/task/container"
+	"github.com/verizonlabs/mesos-framework-sdk/task/healthcheck"
+	"github.com/verizonlabs/mesos-framework-sdk/task/labels"
+	"github.com/verizonlabs/mesos-framework-sdk/task/manager"
+	"github.com/verizonlabs/mesos-framework-sdk/task/network"
+	taskResources "github.com/verizonlabs/mesos-framework-sdk/task/resources"
+	"github.com/verizonlabs/mesos-framework-sdk/task/retry"
+	"github.com/verizonlabs/mesos-framework-sdk/utils"
+	"strings"
+	"time"
+)
+
+// The default executor needs a little room of its own if the pod doesn't say otherwise.
+const (
+	DEFAULT_EXECUTOR_CPU = 0.1
+	DEFAULT_EXECUTOR_MEM = 32.0
+)
+
+// Parses a pod into the default executor and the tasks it runs.
+// Task IDs are generated so that every parsed pod can be launched as a new group.
+// The framework ID on the executor is left for the caller to fill in.
+func ParsePod(pod *task.PodJSON) (*mesos_v1.ExecutorInfo, []*mesos_v1.TaskInfo, error) {
+	if pod == nil || pod.Name == "" {
+		return nil, nil, errors.New("Pod must have a name.")
+	}
+
+	if len(pod.Tasks) == 0 {
+		return nil, nil, errors.New("Pod must have at least one task.")
+	}
+
+	res, err := parseExecutorResources(pod.Resources)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	// Tasks join the executor's network namespace, so networks are only ever set here.
+	var networks []*mesos_v1.NetworkInfo
+	if len(pod.Network) > 0 {
+		networks, err = network.ParseNetworkJSON(pod.Network)
+		if err != nil {
+			return nil, nil, err
+		}
+	}
+
+	l, err := labels.ParseLabels(pod.Labels)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var sandbox, shared []*mesos_v1.Volume
+	for _, v := range pod.Volumes {
+		self, parent, err := parseVolume(v)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		sandbox = append(sandbox, self)
+		shared = append(shared, parent)
+	}
+
+	executor := resources.CreateDefaultExecutorInfo(
+		&mesos_v1.ExecutorID{Value: utils.ProtoString(pod.Name + "-" + utils.UuidAsString())},
+		nil,
+		res,
+		&mesos_v1.ContainerInfo{
+			Type:         mesos_v1.ContainerInfo_MESOS.Enum(),
+			NetworkInfos: networks,
+			Volumes:      sandbox,
+		},
+	)
+	executor.Name = utils.ProtoString(pod.Name)
+	executor.Labels = l
+
+	tasks := make([]*mesos_v1.TaskInfo, 0, len(pod.Tasks))
+	for i := range pod.Tasks {
+		t, err := parseTask(pod.Name, &pod.Tasks[i], shared)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		tasks = append(tasks, t)
+	}
+
+	return executor, tasks, nil
+}
+
+// Parses every instance of a pod into a task group that's ready to be added through a GroupManager.
+// Each instance gets its own executor, so groups are named after their executor ID to tell them apart.
+func ParsePodGroups(pod *task.PodJSON, state mesos_v1.TaskState) ([]*manager.TaskGroup, error) {
+	if pod == nil {
+		return nil, errors.New("Pod must have a name.")
+	}
+
+	r, err := parseRetry(pod.Retry)
+	if err != nil {
+		return nil, err
+	}
+
+	instances := pod.Instances
+	if instances < 1 {
+		instances = 1
+	}
+
+	groups := make([]*manager.TaskGroup, 0, instances)
+	for i := 0; i < instances; i++ {
+		executor, tasks, err := ParsePod(pod)
+		if err != nil {
+			return nil, err
+		}
+
+		// Every instance gets its own copy of the retry policy since the task manager counts retries on it.
+		var policy *retry.TaskRetry
+		if r != nil {
+			copied := *r
+			policy = &copied
+		}
+
+		groups = append(groups, manager.NewTaskGroup(
+			executor.GetExecutorId().GetValue(),
+			executor,
+			tasks,
+			state,
+			pod.Filters,
+			policy,
+			pod.Strategy,
+		))
+	}
+
+	return groups, nil
+}
+
+// Turns the pod's retry policy into one the task manager understands.
+func parseRetry(r *task.TimeRetry) (*retry.TaskRetry, error) {
+	if r == nil {
+		return nil, nil
+	}
+
+	policy := &retry.TaskRetry{
+		MaxRetries: r.MaxRetries,
+		Backoff:    r.Backoff,
+	}
+
+	if r.Time != "" {
+		t, err := time.ParseDuration(r.Time)
+		if err != nil {
+			return nil, errors.New("Invalid retry time " + r.Time + ": " + err.Error())
+		}
+		policy.RetryTime = t
+	}
+
+	return policy, nil
+}
+
+// Uses the pod's resources for the executor if given, otherwise falls back to our defaults.
+func parseExecutorResources(res *task.ResourceJSON) ([]*mesos_v1.Resource, error) {
+	if res == nil {
+		return []*mesos_v1.Resource{
+			resources.CreateResource("cpus", "", DEFAULT_EXECUTOR_CPU),
+			resources.CreateResource("mem", "", DEFAULT_EXECUTOR_MEM),
+		}, nil
+	}
+
+	return taskResources.ParseResources(res)
+}
+
+// Creates the directory in the executor's sandbox along with the volume that tasks use to mount it.
+func parseVolume(v task.PodVolumeJSON) (*mesos_v1.Volume, *mesos_v1.Volume, error) {
+	if v.Name == "" || v.ContainerPath == "" {
+		return nil, nil, errors.New("Pod volumes must have a name and container path.")
+	}
+
+	mode := mesos_v1.Volume_RW.Enum()
+	if v.Mode != nil && strings.ToLower(*v.Mode) == "ro" {
+		mode = mesos_v1.Volume_RO.Enum()
+	}
+
+	self := &mesos_v1.Volume{
+		Mode:          mesos_v1.Volume_RW.Enum(),
+		ContainerPath: utils.ProtoString(v.Name),
+		Source: &mesos_v1.Volume_Source{
+			Type: mesos_v1.Volume_Source_SANDBOX_PATH.Enum(),
+			SandboxPath: &mesos_v1.Volume_Source_SandboxPath{
+				Type: mesos_v1.Volume_Source_SandboxPath_SELF.Enum(),
+				Path: utils.ProtoString(v.Name),
+			},
+		},
+	}
+
+	parent := &mesos_v1.Volume{
+		Mode:          mode,
+		ContainerPath: utils.ProtoString(v.ContainerPath),
+		Source: &mesos_v1.Volume_Source{
+			Type: mesos_v1.Volume_Source_SANDBOX_PATH.Enum(),
+			SandboxPath: &mesos_v1.Volume_Source_SandboxPath{
+				Type: mesos_v1.Volume_Source_SandboxPath_PARENT.Enum(),
+				Path: utils.ProtoString(v.Name),
+			},
+		},
+	}
+
+	return self, parent, nil
+}
+
+// Parses a single member of the pod.
+func parseTask(pod string, app *task.ApplicationJSON, shared []*mesos_v1.Volume) (*mesos_v1.TaskInfo, error) {
+	if app.Name == "" {
+		return nil, errors.New("Every task in a pod must have a name.")
+	}
+
+	if app.Resources == nil {
+		return nil, errors.New("Task " + app.Name + " in pod " + pod + " has no resources.")
+	}
+
+	res, err := taskResources.ParseResources(app.Resources)
+	if err != nil {
+		return nil, err
+	}
+
+	var cmd *mesos_v1.CommandInfo
+	if app.Command != nil {
+		cmd, err = command.ParseCommandInfo(app.Command)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if app.Container != nil && len(app.Container.Network) > 0 {
+		return nil, errors.New("Task " + app.Name + " cannot set its own network, tasks share the network of pod " + pod + ".")
+	}
+
+	con, err := container.ParseContainer(app.Container)
+	if err != nil {
+		return nil, err
+	}
+
+	if con == nil {
+		con = &mesos_v1.ContainerInfo{Type: mesos_v1.ContainerInfo_MESOS.Enum()}
+	}
+	con.NetworkInfos = nil
+	con.Volumes = append(con.Volumes, shared...)
+
+	hc, err := healthcheck.ParseHealthCheck(app.HealthCheck, cmd)
+	if err != nil {
+		return nil, err
+	}
+
+	l, err := labels.ParseLabels(app.Labels)
+	if err != nil {
+		return nil, err
+	}
+
+	name := pod + "-" + app.Name
+
+	return resources.CreateTaskInfo(
+		utils.ProtoString(name),
+		&mesos_v1.TaskID{Value: utils.ProtoString(name + "-" + utils.UuidAsString())},
+		cmd,
+		res,
+		con,
+		hc,
+		l,
+	), nil
+}
@@ -0,0 +1,206 @@
+// Copyright 2017 Verizon
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package pod
+
+import (
+	"encoding/json"
+	"github.com/verizonlabs/mesos-framework-sdk/include/mesos_v1"
+	"github.com/verizonlabs/mesos-framework-sdk/task"
+	"github.com/verizonlabs/mesos-framework-sdk/task/manager"
+	"testing"
+	"time"
+)
+
+var podJSON = []byte(`{
+	"name": "web",
+	"instances": 2,
+	"network": [{"name": "overlay"}],
+	"volumes": [{"name": "scratch", "container_path": "/scratch", "mode": "ro"}],
+	"labels": {"team": "edge"},
+	"filters": [{"type": "TEXT", "value": ["rack-1"]}],
+	"retry": {"time": "2s", "exp_backoff": true, "total_retries": 3},
+	"strategy": {"type": "spread"},
+	"tasks": [
+		{"name": "server", "resources": {"cpu": 0.5, "mem": 128, "disk": {"size": 64}}},
+		{"name": "sidecar", "resources": {"cpu": 0.1, "mem": 32, "disk": {"size": 16}}}
+	]
+}`)
+
+func mockPod(t *testing.T) *task.PodJSON {
+	pod := new(task.PodJSON)
+	if err := json.Unmarshal(podJSON, pod); err != nil {
+		t.Fatal(err.Error())
+	}
+
+	return pod
+}
+
+// Checks that the executor gets the pod's network, labels and volumes while the tasks mount the shared volumes.
+func TestParsePod(t *testing.T) {
+	t.Parallel()
+
+	executor, tasks, err := ParsePod(mockPod(t))
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	if executor.GetType() != mesos_v1.ExecutorInfo_DEFAULT || executor.GetName() != "web" {
+		t.Fatal("Pods must run on the default executor named after the pod")
+	}
+
+	networks := executor.GetContainer().GetNetworkInfos()
+	if len(networks) != 1 || networks[0].GetName() != "overlay" {
+		t.Fatal("The executor should join the pod's network")
+	}
+
+	l := executor.GetLabels().GetLabels()
+	if len(l) != 1 || l[0].GetKey() != "team" || l[0].GetValue() != "edge" {
+		t.Fatal("The executor should carry the pod's labels")
+	}
+
+	if len(executor.GetContainer().GetVolumes()) != 1 {
+		t.Fatal("The executor should create the pod's volumes in its sandbox")
+	}
+
+	if len(tasks) != 2 || tasks[0].GetName() != "web-server" || tasks[1].GetName() != "web-sidecar" {
+		t.Fatal("Every task in the pod should be parsed and named after it")
+	}
+
+	for _, info := range tasks {
+		volumes := info.GetContainer().GetVolumes()
+		if len(volumes) != 1 || volumes[0].GetContainerPath() != "/scratch" || volumes[0].GetMode() != mesos_v1.Volume_RO {
+			t.Fatal("Tasks should mount the pod's volumes as declared")
+		}
+		if len(info.GetContainer().GetNetworkInfos()) != 0 {
+			t.Fatal("Tasks should share the executor's network instead of having their own")
+		}
+	}
+}
+
+// Makes sure the executor's defaults are used and an empty network list isn't treated as an error.
+func TestParsePod_Defaults(t *testing.T) {
+	t.Parallel()
+
+	pod := mockPod(t)
+	pod.Network = nil
+	pod.Labels = nil
+
+	executor, _, err := ParsePod(pod)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	if len(executor.GetContainer().GetNetworkInfos()) != 0 {
+		t.Fatal("Pods without a network should use host networking")
+	}
+
+	for _, r := range executor.GetResources() {
+		switch r.GetName() {
+		case "cpus":
+			if r.GetScalar().GetValue() != DEFAULT_EXECUTOR_CPU {
+				t.Fatal("The executor should get the default amount of cpu")
+			}
+		case "mem":
+			if r.GetScalar().GetValue() != DEFAULT_EXECUTOR_MEM {
+				t.Fatal("The executor should get the default amount of memory")
+			}
+		}
+	}
+}
+
+// Checks that invalid pods are rejected.
+func TestParsePod_Invalid(t *testing.T) {
+	t.Parallel()
+
+	if _, _, err := ParsePod(nil); err == nil {
+		t.Fatal("A missing pod should be rejected")
+	}
+
+	pod := mockPod(t)
+	pod.Tasks = nil
+	if _, _, err := ParsePod(pod); err == nil {
+		t.Fatal("A pod without tasks should be rejected")
+	}
+
+	pod = mockPod(t)
+	pod.Tasks[0].Resources = nil
+	if _, _, err := ParsePod(pod); err == nil {
+		t.Fatal("A task without resources should be rejected")
+	}
+
+	pod = mockPod(t)
+	pod.Volumes[0].ContainerPath = ""
+	if _, _, err := ParsePod(pod); err == nil {
+		t.Fatal("A volume without a container path should be rejected")
+	}
+
+	pod = mockPod(t)
+	pod.Labels = map[string]string{"team": ""}
+	if _, _, err := ParsePod(pod); err == nil {
+		t.Fatal("Invalid labels should be rejected")
+	}
+
+	pod = mockPod(t)
+	pod.Tasks[0].Container = &task.ContainerJSON{Network: []task.NetworkJSON{{}}}
+	if _, _, err := ParsePod(pod); err == nil {
+		t.Fatal("Tasks should not be allowed to set their own network")
+	}
+}
+
+// Makes sure every instance becomes its own group carrying the pod's filters, retry policy and strategy.
+func TestParsePodGroups(t *testing.T) {
+	t.Parallel()
+
+	groups, err := ParsePodGroups(mockPod(t), manager.UNKNOWN)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	if len(groups) != 2 {
+		t.Fatal("Every instance of the pod should become a group")
+	}
+
+	if groups[0].Name == groups[1].Name {
+		t.Fatal("Instances of a pod need different group names")
+	}
+
+	for _, g := range groups {
+		if g.Name != g.Executor.GetExecutorId().GetValue() {
+			t.Fatal("Groups should be named after their executor")
+		}
+
+		for _, member := range g.Tasks {
+			if !member.GroupInfo.InGroup || member.GroupInfo.GroupName != g.Name || member.GroupInfo.Executor != g.Executor {
+				t.Fatal("Members should know which group they belong to")
+			}
+			if member.State != manager.UNKNOWN || len(member.Filters) != 1 || member.Strategy.Type != "spread" {
+				t.Fatal("Members should get the pod's state, filters and strategy")
+			}
+			if member.Retry.RetryTime != 2*time.Second || !member.Retry.Backoff || member.Retry.MaxRetries != 3 {
+				t.Fatal("Members should get the pod's retry policy")
+			}
+		}
+	}
+
+	if groups[0].Tasks[0].Retry == groups[1].Tasks[0].Retry {
+		t.Fatal("Instances should not share retry counters")
+	}
+
+	pod := mockPod(t)
+	pod.Retry.Time = "soon"
+	if _, err := ParsePodGroups(pod, manager.UNKNOWN); err == nil {
+		t.Fatal("An invalid retry time should be rejected")
+	}
+}
@@ -19,6 +19,7 @@ import (
 	"github.com/verizonlabs/mesos-framework-sdk/include/mesos_v1"
 	"github.com/verizonlabs/mesos-framework-sdk/resources"
 	"github.com/verizonlabs/mesos-framework-sdk/task"
+	"sort"
 )
 
 func ParseResources(res *task.ResourceJSON) ([]*mesos_v1.Resource, error) {
@@ -36,5 +37,27 @@ func ParseResources(res *task.ResourceJSON) ([]*mesos_v1.Resource, error) {
 		return nil, err
 	}
 
-	return []*mesos_v1.Resource{cpu, mem, disk}, nil
+	parsed := []*mesos_v1.Resource{cpu, mem, disk}
+	if res.Gpu > 0 {
+		parsed = append(parsed, resources.CreateResource("gpus", res.Role, res.Gpu))
+	}
+	if len(res.Ports) > 0 {
+		parsed = append(parsed, resources.CreatePortsResource(res.Role, res.Ports...))
+	}
+
+	// Sorted so tasks always ask for their resources in the same order.
+	names := make([]string, 0, len(res.Scalars))
+	for name := range res.Scalars {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		if res.Scalars[name] <= 0 {
+			return nil, errors.New("Resource " + name + " must be greater than 0.0.")
+		}
+		parsed = append(parsed, resources.CreateResource(name, res.Role, res.Scalars[name]))
+	}
+
+	return parsed, nil
 }
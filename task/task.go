@@ -27,9 +27,32 @@ type ApplicationJSON struct {
 	Strategy    Strategy          `json:"strategy"`
 }
 
+// Describes a group of tasks that are launched together on the default executor.
+// Tasks in a pod share the pod's network namespace along with any volumes it declares.
+type PodJSON struct {
+	Name      string            `json:"name"`
+	Instances int               `json:"instances"`
+	Resources *ResourceJSON     `json:"resources"` // Resources for the executor itself, on top of the tasks.
+	Network   []NetworkJSON     `json:"network"`
+	Volumes   []PodVolumeJSON   `json:"volumes"`
+	Tasks     []ApplicationJSON `json:"tasks"`
+	Labels    map[string]string `json:"labels"`
+	Filters   []Filter          `json:"filters"`
+	Retry     *TimeRetry        `json:"retry"`
+	Strategy  Strategy          `json:"strategy"`
+}
+
+// Scratch space in the pod's sandbox that every task mounts at the same container path.
+type PodVolumeJSON struct {
+	Name          string  `json:"name"`
+	ContainerPath string  `json:"container_path"`
+	Mode          *string `json:"mode"`
+}
+
 type Strategy struct {
-	Effort string `json:"effort"`
-	Type   string `json:"type"`
+	Effort    string `json:"effort"`
+	Type      string `json:"type"`
+	Attribute string `json:"attribute"` // Agent attribute to spread across, agents themselves are used if empty.
 }
 
 type TimeRetry struct {
@@ -72,10 +95,13 @@ type KillJson struct {
 }
 
 type ResourceJSON struct {
-	Mem  float64 `json:"mem"`
-	Cpu  float64 `json:"cpu"`
-	Disk Disk    `json:"disk"`
-	Role string  `json:"role"`
+	Mem     float64            `json:"mem"`
+	Cpu     float64            `json:"cpu"`
+	Disk    Disk               `json:"disk"`
+	Role    string             `json:"role"`
+	Gpu     float64            `json:"gpu"`
+	Ports   []uint64           `json:"ports"`   // Port numbers to reserve, 0 asks for any free port.
+	Scalars map[string]float64 `json:"scalars"` // Any other scalar resources agents advertise, keyed by name.
 }
 
 type Disk struct {